@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrorKind tells which step of processing a file failed
+type ErrorKind int
+
+const (
+	ReadError ErrorKind = iota
+	ParseError
+	SortError
+	VerifyError
+	WriteError
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ReadError:
+		return "read error"
+	case ParseError:
+		return "parse error"
+	case SortError:
+		return "sort error"
+	case VerifyError:
+		return "verify error"
+	case WriteError:
+		return "write error"
+	}
+	return "error"
+}
+
+// FileError is the error for one file. Run keeps going after a file fails
+// and returns the errors of all files joined with errors.Join; use
+// errors.As or FileErrors to get at them.
+type FileError struct {
+	Path string
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Path, e.Kind, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors returns the file errors contained in an error returned by Run
+func FileErrors(err error) []*FileError {
+	var fileErrs []*FileError
+	for _, err := range splitErrors(err) {
+		var fileErr *FileError
+		if errors.As(err, &fileErr) {
+			fileErrs = append(fileErrs, fileErr)
+		}
+	}
+	return fileErrs
+}
+
+// WriteSummary prints an error returned by Run one failure per line, after
+// the number of files that failed
+func WriteSummary(w io.Writer, err error) {
+	indent := ""
+	if count := len(FileErrors(err)); count > 0 {
+		fmt.Fprintf(w, "%d %s failed:\n", count, plural(count, "file", "files"))
+		indent = "  "
+	}
+
+	for _, err := range splitErrors(err) {
+		fmt.Fprintf(w, "%s%v\n", indent, err)
+	}
+}
+
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func plural(count int, one, many string) string {
+	if count == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+)
+
+func TestRunReportsFileErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"broken.go": "package test\n\nfunc broken( {\n",
+		// The section comment is separated from helper by a blank line, so
+		// strict mode refuses to move it
+		"ambiguous.go": `package test
+
+type Server struct{}
+
+// Helpers
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() {}
+`,
+		"fine.go": "package test\n\ntype Client struct{}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := msortconfig.DefaultConfig()
+	settings.SortCriteria.StrictComments = true
+
+	err := Run(&Config{Paths: []string{tmpDir}, Settings: settings})
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+
+	expected := []struct {
+		name string
+		kind ErrorKind
+	}{
+		{"ambiguous.go", SortError},
+		{"broken.go", ParseError},
+	}
+
+	fileErrs := FileErrors(err)
+	if len(fileErrs) != len(expected) {
+		t.Fatalf("Expected %d file errors, got %v", len(expected), fileErrs)
+	}
+	for i, want := range expected {
+		if filepath.Base(fileErrs[i].Path) != want.name || fileErrs[i].Kind != want.kind {
+			t.Errorf("File error %d = %s (%s), want %s (%s)",
+				i, fileErrs[i].Path, fileErrs[i].Kind, want.name, want.kind)
+		}
+	}
+
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Error("Expected errors.As to find a *FileError")
+	}
+}
+
+func TestRunWithUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+
+	tmpDir := t.TempDir()
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() {}
+`
+	for name, content := range map[string]string{
+		"go.mod":           "module testmodule\n\ngo 1.22\n",
+		"server.go":        unsorted,
+		"locked/locked.go": unsorted,
+	} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locked := filepath.Join(tmpDir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0755) })
+
+	// The unreadable directory is reported, and the rest is still sorted
+	err := Run(&Config{Paths: []string{tmpDir}})
+	fileErrs := FileErrors(err)
+	if len(fileErrs) != 1 || fileErrs[0].Path != locked || fileErrs[0].Kind != ReadError {
+		t.Errorf("Expected a read error for %s, got %v", locked, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "server.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) == unsorted {
+		t.Error("Expected server.go to be sorted despite the unreadable directory")
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	parseErr := &FileError{Path: "a.go", Kind: ParseError, Err: errors.New("expected ')'")}
+	writeErr := &FileError{Path: "b.go", Kind: WriteError, Err: errors.New("permission denied")}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name: "file errors",
+			err:  errors.Join(parseErr, writeErr),
+			expected: "2 files failed:\n" +
+				"  a.go: parse error: expected ')'\n" +
+				"  b.go: write error: permission denied\n",
+		},
+		{
+			name:     "single file",
+			err:      errors.Join(parseErr),
+			expected: "1 file failed:\n  a.go: parse error: expected ')'\n",
+		},
+		{
+			name:     "other error",
+			err:      errors.New("loading config: no such file"),
+			expected: "loading config: no such file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WriteSummary(&buf, tt.err)
+			if buf.String() != tt.expected {
+				t.Errorf("WriteSummary() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+	"github.com/borovikovd/gomsort/pkg/sorter"
+)
+
+// FormatText is the default format of the explain command, which also
+// takes FormatJSON
+const FormatText = "text"
+
+type ExplainConfig struct {
+	// Paths are Go files, each optionally followed by ":Type" to explain
+	// only the methods of that receiver type
+	Paths      []string
+	Format     string
+	ConfigPath string
+
+	// TypeCheck and Package compute the metrics as for Run
+	TypeCheck bool
+	Package   bool
+
+	// Strategy, if set, overrides the strategy of the configuration file
+	Strategy string
+
+	// Settings holds the loaded configuration file, as for Run
+	Settings *msortconfig.Config
+}
+
+type explainJSON struct {
+	Files []fileExplanation `json:"files"`
+}
+
+type fileExplanation struct {
+	Path      string                `json:"path"`
+	Receivers []receiverExplanation `json:"receivers"`
+}
+
+type receiverExplanation struct {
+	Name    string              `json:"name"`
+	Methods []methodExplanation `json:"methods"`
+}
+
+type methodExplanation struct {
+	Name          string `json:"name"`
+	Exported      bool   `json:"exported"`
+	MaxDepth      int    `json:"max_depth"`
+	InDegree      int    `json:"in_degree"`
+	Index         int    `json:"index"`
+	OriginalIndex int    `json:"original_index"`
+	Line          int    `json:"line,omitempty"`
+	Column        int    `json:"column,omitempty"`
+
+	// After and Criterion tell which rule puts the method after the one
+	// before it; Moved explains why the sort moves it, if it does
+	After     string `json:"after,omitempty"`
+	Criterion string `json:"criterion,omitempty"`
+	Moved     string `json:"moved,omitempty"`
+
+	criterion sorter.Criterion
+}
+
+// Explain writes to w, for each file in config.Paths, the order the sort
+// puts the methods in, receiver by receiver, with the metrics of each method
+// and the rule that places it after the one before it
+func Explain(w io.Writer, config *ExplainConfig) error {
+	switch config.Format {
+	case "":
+		config.Format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown explain format %q (want %s or %s)", config.Format, FormatText, FormatJSON)
+	}
+
+	if config.Settings == nil {
+		settings, err := msortconfig.Load(config.ConfigPath)
+		if err != nil {
+			return err
+		}
+		config.Settings = settings
+	}
+	settings, err := withStrategy(config.Settings, config.Strategy)
+	if err != nil {
+		return err
+	}
+	config.Settings = settings
+
+	// Sorters are loaded the same way as for sorting, so that the metrics
+	// match what sort does
+	sortConfig := &Config{
+		TypeCheck: config.TypeCheck,
+		Package:   config.Package,
+		Settings:  config.Settings,
+	}
+
+	var files []fileExplanation
+	for _, path := range config.Paths {
+		filename, typeName := splitTypeSuffix(path)
+		explanation, err := explainFile(filename, typeName, sortConfig)
+		if err != nil {
+			return err
+		}
+		files = append(files, explanation)
+	}
+
+	if config.Format == FormatJSON {
+		data, err := json.MarshalIndent(explainJSON{Files: files}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := writeExplanation(w, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitTypeSuffix splits "file.go:Type" into the file and the type
+func splitTypeSuffix(path string) (string, string) {
+	i := strings.LastIndex(path, ":")
+	if i < 0 || !strings.HasSuffix(path[:i], ".go") || strings.ContainsAny(path[i+1:], `/\`) {
+		return path, ""
+	}
+	return path[:i], path[i+1:]
+}
+
+func explainFile(filename, typeName string, config *Config) (fileExplanation, error) {
+	explanation := fileExplanation{Path: filename, Receivers: []receiverExplanation{}}
+
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return explanation, &FileError{Path: filename, Kind: ReadError, Err: err}
+	}
+
+	methodSorter, err := explainSorter(filename, source, config)
+	if err != nil {
+		return explanation, &FileError{Path: filename, Kind: ParseError, Err: err}
+	}
+	methodSorter.SetCriteria(config.Settings.SortCriteria)
+
+	if _, _, err := methodSorter.Sort(); err != nil {
+		return explanation, &FileError{Path: filename, Kind: SortError, Err: err}
+	}
+
+	moved := make(map[string]string)
+	for _, misplaced := range methodSorter.Misplaced() {
+		moved[misplaced.ReceiverName+"."+misplaced.Name] = misplaced.Message
+	}
+
+	index := make(map[string]int)
+	for _, placement := range methodSorter.Placements() {
+		if typeName != "" && placement.ReceiverName != typeName {
+			continue
+		}
+
+		i, ok := index[placement.ReceiverName]
+		if !ok {
+			i = len(explanation.Receivers)
+			index[placement.ReceiverName] = i
+			explanation.Receivers = append(explanation.Receivers, receiverExplanation{Name: placement.ReceiverName})
+		}
+
+		position := methodSorter.Position(placement.Pos)
+		method := methodExplanation{
+			Name:          placement.Name,
+			Exported:      placement.IsExported,
+			MaxDepth:      placement.MaxDepth,
+			InDegree:      placement.InDegree,
+			Index:         placement.To,
+			OriginalIndex: placement.From,
+			Line:          position.Line,
+			Column:        position.Column,
+			After:         placement.After,
+			Moved:         moved[placement.ReceiverName+"."+placement.Name],
+			criterion:     placement.Criterion,
+		}
+		if placement.After != "" {
+			method.Criterion = placement.Criterion.String()
+		}
+		explanation.Receivers[i].Methods = append(explanation.Receivers[i].Methods, method)
+	}
+
+	if typeName != "" && len(explanation.Receivers) == 0 {
+		return explanation, fmt.Errorf("no methods of type %s in %s", typeName, filename)
+	}
+	return explanation, nil
+}
+
+// explainSorter returns a sorter as newSorter does, but built from the
+// parsed file where possible, so that methods have positions
+func explainSorter(filename string, source []byte, config *Config) (*sorter.Sorter, error) {
+	if config.typeCheck() || config.Package {
+		return newSorter(filename, source, config)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return sorter.NewFromFile(fset, file, nil)
+}
+
+func writeExplanation(w io.Writer, file fileExplanation) error {
+	fmt.Fprintln(w, file.Path)
+	if len(file.Receivers) == 0 {
+		fmt.Fprintln(w, "  no methods")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var moved []string
+	for _, receiver := range file.Receivers {
+		fmt.Fprintf(tw, "  %s\n", receiver.Name)
+		for _, method := range receiver.Methods {
+			visibility := "unexported"
+			if method.Exported {
+				visibility = "exported"
+			}
+			fmt.Fprintf(tw, "    %d\t%s\t%s\tdepth %d\tin-degree %d\twas %d",
+				method.Index+1, method.Name, visibility, method.MaxDepth, method.InDegree, method.OriginalIndex+1)
+			if method.After != "" {
+				fmt.Fprintf(tw, "\tafter %s: %s", method.After, criterionReason(method.criterion))
+			}
+			fmt.Fprintln(tw)
+
+			if method.Moved != "" {
+				location := file.Path
+				if method.Line > 0 {
+					location = fmt.Sprintf("%s:%d:%d", file.Path, method.Line, method.Column)
+				}
+				moved = append(moved, fmt.Sprintf("%s: %s", location, method.Moved))
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(moved) > 0 {
+		fmt.Fprintln(w, "  moved:")
+		for _, line := range moved {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	return nil
+}
+
+// criterionReason describes a criterion the way it orders two methods
+func criterionReason(criterion sorter.Criterion) string {
+	switch criterion {
+	case sorter.CriterionReceiver:
+		return "grouped by receiver"
+	case sorter.CriterionExported:
+		return "exported first"
+	case sorter.CriterionDepth:
+		return "lower call depth first"
+	case sorter.CriterionInDegree:
+		return "higher in-degree first"
+	case sorter.CriterionPosition:
+		return "original order"
+	case sorter.CriterionCallOrder:
+		return "call order"
+	case sorter.CriterionStrategy:
+		return "strategy order"
+	}
+	return "tie, order kept"
+}
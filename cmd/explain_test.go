@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeExplainFiles(t *testing.T) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	unsorted := filepath.Join(tmpDir, "unsorted.go")
+	if err := os.WriteFile(unsorted, []byte(`package test
+
+type Server struct{}
+
+func (s *Server) connect() {}
+
+func (s *Server) Start() { s.connect() }
+
+type Client struct{}
+
+func (c *Client) Do() {}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sorted := filepath.Join(tmpDir, "sorted.go")
+	if err := os.WriteFile(sorted, []byte(`package test
+
+func (s *Server) Stop() {}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return unsorted, sorted
+}
+
+func TestExplain(t *testing.T) {
+	unsorted, sorted := writeExplainFiles(t)
+
+	var buf bytes.Buffer
+	if err := Explain(&buf, &ExplainConfig{Paths: []string{unsorted, sorted}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := unsorted + "\n" +
+		"  Client\n" +
+		"    1  Do  exported  depth 0  in-degree 0  was 3\n" +
+		"  Server\n" +
+		"    2  Start    exported    depth 1  in-degree 0  was 2\n" +
+		"    3  connect  unexported  depth 0  in-degree 1  was 1  after Start: exported first\n" +
+		"  moved:\n" +
+		"    " + unsorted + ":11:18: methods of Client should precede methods of Server\n" +
+		"    " + unsorted + ":7:18: exported method Start should precede unexported connect\n" +
+		"\n" +
+		sorted + "\n" +
+		"  Server\n" +
+		"    1  Stop  exported  depth 0  in-degree 0  was 1\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestExplainJSON(t *testing.T) {
+	unsorted, _ := writeExplainFiles(t)
+
+	var buf bytes.Buffer
+	config := &ExplainConfig{Paths: []string{unsorted + ":Server"}, Format: FormatJSON}
+	if err := Explain(&buf, config); err != nil {
+		t.Fatal(err)
+	}
+
+	var result explainJSON
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(result.Files) != 1 || result.Files[0].Path != unsorted {
+		t.Fatalf("Expected one file %s, got %+v", unsorted, result.Files)
+	}
+
+	receivers := result.Files[0].Receivers
+	if len(receivers) != 1 || receivers[0].Name != "Server" {
+		t.Fatalf("Expected only Server, got %+v", receivers)
+	}
+
+	expected := []methodExplanation{
+		{Name: "Start", Exported: true, MaxDepth: 1, Index: 1, OriginalIndex: 1, Line: 7, Column: 18,
+			Moved: "exported method Start should precede unexported connect"},
+		{Name: "connect", InDegree: 1, Index: 2, OriginalIndex: 0, Line: 5, Column: 18,
+			After: "Start", Criterion: "exported"},
+	}
+	if !reflect.DeepEqual(receivers[0].Methods, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, receivers[0].Methods)
+	}
+}
+
+func TestExplainUnknownType(t *testing.T) {
+	unsorted, _ := writeExplainFiles(t)
+
+	err := Explain(&bytes.Buffer{}, &ExplainConfig{Paths: []string{unsorted + ":Missing"}})
+	if err == nil || !strings.Contains(err.Error(), "no methods of type Missing") {
+		t.Errorf("Expected an error for the unknown type, got %v", err)
+	}
+}
+
+func TestExplainStrategy(t *testing.T) {
+	unsorted, _ := writeExplainFiles(t)
+
+	var buf bytes.Buffer
+	config := &ExplainConfig{Paths: []string{unsorted + ":Server"}, Strategy: "stepdown"}
+	if err := Explain(&buf, config); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "after Start: call order\n") {
+		t.Errorf("Expected connect to follow Start in call order, got:\n%s", buf.String())
+	}
+
+	config = &ExplainConfig{Paths: []string{unsorted}, Strategy: "alphabetical"}
+	if err := Explain(&bytes.Buffer{}, config); err == nil || !strings.Contains(err.Error(), "unknown strategy") {
+		t.Errorf("Expected an error for the strategy, got %v", err)
+	}
+}
+
+func TestExplainUnknownFormat(t *testing.T) {
+	unsorted, _ := writeExplainFiles(t)
+
+	err := Explain(&bytes.Buffer{}, &ExplainConfig{Paths: []string{unsorted}, Format: "yaml"})
+	if err == nil || !strings.Contains(err.Error(), "unknown explain format") {
+		t.Errorf("Expected an error for the format, got %v", err)
+	}
+}
+
+func TestSplitTypeSuffix(t *testing.T) {
+	tests := []struct {
+		path     string
+		filename string
+		typeName string
+	}{
+		{"server.go", "server.go", ""},
+		{"server.go:Server", "server.go", "Server"},
+		{"dir/server.go:Server", "dir/server.go", "Server"},
+		{"C:/src/server.go", "C:/src/server.go", ""},
+		{"C:/src/server.go:Server", "C:/src/server.go", "Server"},
+		{"server.txt:Server", "server.txt:Server", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			filename, typeName := splitTypeSuffix(tt.path)
+			if filename != tt.filename || typeName != tt.typeName {
+				t.Errorf("Expected (%q, %q), got (%q, %q)", tt.filename, tt.typeName, filename, typeName)
+			}
+		})
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	broken := filepath.Join(tmpDir, "broken.go")
+	if err := os.WriteFile(broken, []byte("package test\n\nfunc broken( {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		kind ErrorKind
+	}{
+		{"missing file", filepath.Join(tmpDir, "missing.go"), ReadError},
+		{"parse error", broken, ParseError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Explain(&bytes.Buffer{}, &ExplainConfig{Paths: []string{tt.path}})
+			fileErrors := FileErrors(err)
+			if len(fileErrors) != 1 || fileErrors[0].Kind != tt.kind {
+				t.Errorf("Expected a %v, got %v", tt.kind, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.path) {
+				t.Errorf("Expected the error to name %s, got %v", tt.path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/borovikovd/gomsort/pkg/sorter"
+)
+
+// Formats of the graph command
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+type GraphConfig struct {
+	// Path is a Go file, or a directory whose package is shown as a whole
+	Path      string
+	Format    string
+	TypeCheck bool
+
+	// Type limits the graph to the methods of one receiver type
+	Type string
+}
+
+// Graph writes the method call graph of config.Path to w, with the in-degree
+// and depth that methods are sorted by
+func Graph(w io.Writer, config *GraphConfig) error {
+	switch config.Format {
+	case FormatDOT, FormatMermaid, FormatJSON:
+	default:
+		return fmt.Errorf("unknown graph format %q (want %s, %s or %s)", config.Format, FormatDOT, FormatMermaid, FormatJSON)
+	}
+
+	graph, err := loadGraph(config.Path, config.TypeCheck)
+	if err != nil {
+		return err
+	}
+
+	if config.Type != "" {
+		graph = graph.ForType(config.Type)
+		if graph.Len() == 0 {
+			return fmt.Errorf("no methods of type %s in %s", config.Type, config.Path)
+		}
+	}
+
+	switch config.Format {
+	case FormatMermaid:
+		return graph.WriteMermaid(w)
+	case FormatJSON:
+		data, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	return graph.WriteDOT(w)
+}
+
+func loadGraph(path string, typeCheck bool) (*sorter.CallGraph, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadFileGraph(path, typeCheck)
+	}
+
+	var sorters map[string]*sorter.Sorter
+	if typeCheck {
+		sorters, err = sorter.LoadPackage(path)
+		if err == nil {
+			sorter.LinkPackage(sorters)
+		}
+	} else {
+		sorters, err = sorter.ParseDir(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The sorters of one package share its graph
+	var graph *sorter.CallGraph
+	for _, methodSorter := range sorters {
+		packageGraph := methodSorter.CallGraph()
+		if graph != nil && packageGraph != graph {
+			return nil, fmt.Errorf("%s holds more than one package", path)
+		}
+		graph = packageGraph
+	}
+	if graph == nil {
+		return nil, fmt.Errorf("no Go files in %s", path)
+	}
+	return graph, nil
+}
+
+func loadFileGraph(path string, typeCheck bool) (*sorter.CallGraph, error) {
+	if !typeCheck {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		methodSorter, err := sorter.NewFromSource(string(source))
+		if err != nil {
+			return nil, err
+		}
+		return methodSorter.CallGraph(), nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sorters, err := sorter.LoadPackage(filepath.Dir(absPath))
+	if err != nil {
+		return nil, err
+	}
+	methodSorter, ok := sorters[absPath]
+	if !ok {
+		return nil, fmt.Errorf("%s is not part of the package in its directory", path)
+	}
+	return methodSorter.CallGraph(), nil
+}
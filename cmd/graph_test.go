@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGraphPackage(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module testmodule\n\ngo 1.22\n",
+		"server.go": `package test
+
+type Server struct{}
+
+func (s *Server) Start() { s.listen() }
+`,
+		"server_net.go": `package test
+
+func (s *Server) listen() {}
+
+type Client struct{}
+
+func (c *Client) Do() {}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpDir
+}
+
+type graphOutput struct {
+	Types []struct {
+		Name    string `json:"name"`
+		Methods []struct {
+			Name     string   `json:"name"`
+			InDegree int      `json:"in_degree"`
+			MaxDepth int      `json:"max_depth"`
+			Calls    []string `json:"calls"`
+		} `json:"methods"`
+	} `json:"types"`
+}
+
+func TestGraphOfPackage(t *testing.T) {
+	dir := writeGraphPackage(t)
+
+	for _, typeCheck := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Graph(&buf, &GraphConfig{Path: dir, Format: FormatJSON, TypeCheck: typeCheck}); err != nil {
+			t.Fatalf("Graph with TypeCheck=%v failed: %v", typeCheck, err)
+		}
+
+		var graph graphOutput
+		if err := json.Unmarshal(buf.Bytes(), &graph); err != nil {
+			t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+		}
+		if len(graph.Types) != 2 || graph.Types[1].Name != "Server" {
+			t.Fatalf("Expected Client and Server, got %+v", graph.Types)
+		}
+
+		// The call crosses files
+		server := graph.Types[1].Methods
+		if len(server) != 2 || server[0].Name != "Start" || server[0].MaxDepth != 1 ||
+			len(server[0].Calls) != 1 || server[0].Calls[0] != "Server.listen" || server[1].InDegree != 1 {
+			t.Errorf("TypeCheck=%v: unexpected Server methods %+v", typeCheck, server)
+		}
+	}
+}
+
+func TestGraphOfType(t *testing.T) {
+	dir := writeGraphPackage(t)
+
+	var buf bytes.Buffer
+	if err := Graph(&buf, &GraphConfig{Path: dir, Format: FormatMermaid, Type: "Server"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Client") || !strings.Contains(buf.String(), "-->") {
+		t.Errorf("Expected only the Server graph, got:\n%s", buf.String())
+	}
+
+	err := Graph(&buf, &GraphConfig{Path: dir, Format: FormatDOT, Type: "Missing"})
+	if err == nil || !strings.Contains(err.Error(), "no methods of type Missing") {
+		t.Errorf("Expected an error for an unknown type, got %v", err)
+	}
+}
+
+func TestGraphOfFile(t *testing.T) {
+	dir := writeGraphPackage(t)
+
+	// On its own, the file does not see that listen is a method of Server
+	var buf bytes.Buffer
+	if err := Graph(&buf, &GraphConfig{Path: filepath.Join(dir, "server.go"), Format: FormatDOT}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "digraph calls {") || strings.Contains(buf.String(), "listen") {
+		t.Errorf("Expected the graph of server.go alone, got:\n%s", buf.String())
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	dir := writeGraphPackage(t)
+
+	tests := []struct {
+		name    string
+		config  GraphConfig
+		wantErr string
+	}{
+		{"unknown format", GraphConfig{Path: dir, Format: "svg"}, `unknown graph format "svg"`},
+		{"missing path", GraphConfig{Path: filepath.Join(dir, "missing"), Format: FormatDOT}, "no such file"},
+		{"empty directory", GraphConfig{Path: t.TempDir(), Format: FormatDOT}, "no Go files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Graph(&bytes.Buffer{}, &tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+)
+
+// DefaultConfigFile is where InitConfig writes by default, the first name
+// that configuration discovery looks for
+const DefaultConfigFile = ".msort.json"
+
+// InitConfig writes the default configuration to path. An existing file is
+// only replaced with force.
+func InitConfig(path string, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists; use -force to overwrite it", path)
+		}
+	}
+
+	if err := msortconfig.DefaultConfig().Save(path); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+)
+
+func TestInitConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+
+	if err := InitConfig(path, false); err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+
+	loaded, err := msortconfig.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := msortconfig.DefaultConfig()
+	if !reflect.DeepEqual(loaded.SortCriteria, expected.SortCriteria) ||
+		!reflect.DeepEqual(loaded.Include, expected.Include) || !reflect.DeepEqual(loaded.Exclude, expected.Exclude) {
+		t.Errorf("Expected the default config, got %+v", loaded)
+	}
+
+	// An existing file is kept unless forced
+	if err := os.WriteFile(path, []byte(`{"exclude": ["vendor"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitConfig(path, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an error for an existing file, got %v", err)
+	}
+
+	if err := InitConfig(path, true); err != nil {
+		t.Fatalf("InitConfig with force failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "vendor") {
+		t.Error("Expected the existing file to be overwritten")
+	}
+}
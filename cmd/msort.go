@@ -1,48 +1,226 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+	"github.com/borovikovd/gomsort/pkg/diff"
 	"github.com/borovikovd/gomsort/pkg/sorter"
 )
 
+// ErrUnsorted is returned by Run in list and check mode when at least one
+// file would be changed
+var ErrUnsorted = errors.New("some files are not sorted")
+
+// stdinName is how source read from standard input is reported
+const stdinName = "<standard input>"
+
 type Config struct {
-	DryRun  bool
-	Verbose bool
-	Paths   []string
+	DryRun     bool
+	Diff       bool
+	List       bool
+	Check      bool
+	ToStdout   bool
+	Verbose    bool
+	Paths      []string
+	ConfigPath string
+	TypeCheck  bool
+	Package    bool
+
+	// Jobs is the number of files processed in parallel; 0 means GOMAXPROCS
+	Jobs int
+
+	// Verify checks every sorted result with sorter.Verify and fails the
+	// file instead of using a result that changed more than the order
+	Verify bool
+
+	// Debug makes the sorter check that its results are idempotent
+	Debug bool
+
+	// Strategy, if set, overrides the strategy of the configuration file
+	Strategy string
+
+	// Settings holds the loaded configuration file. When nil, Run loads it
+	// from ConfigPath or from the discovered .msort.json.
+	Settings *msortconfig.Config
+
+	// Type-checked or package-linked sorters by directory, loaded on first use
+	mu         sync.Mutex
+	dirSorters map[string]*dirSorters
+	unsorted   bool
+	stdin      bool
+}
+
+type dirSorters struct {
+	once    sync.Once
+	sorters map[string]*sorter.Sorter
+	err     error
+}
+
+// fileResult collects what processing one file prints, so that files can be
+// processed in parallel and still reported in order
+type fileResult struct {
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	err      error
+	unsorted bool
 }
 
 func Run(config *Config) error {
+	if config.Settings == nil {
+		settings, err := msortconfig.Load(config.ConfigPath)
+		if err != nil {
+			return err
+		}
+		config.Settings = settings
+	}
+	settings, err := withStrategy(config.Settings, config.Strategy)
+	if err != nil {
+		return err
+	}
+	config.Settings = settings
+
+	config.unsorted = false
+	config.stdin = false
 	for _, path := range config.Paths {
-		if err := processPath(path, config); err != nil {
-			return fmt.Errorf("processing %s: %w", path, err)
+		if path == "-" {
+			config.stdin = true
 		}
 	}
+
+	var errs []error
+	var files []string
+	for _, path := range config.Paths {
+		found, collectErrs := collectFiles(path, config)
+		errs = append(errs, collectErrs...)
+		files = append(files, found...)
+	}
+
+	if config.typeCheck() {
+		config.loadPackages(files)
+	}
+
+	errs = append(errs, processFiles(files, config)...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if config.unsorted && (config.List || config.Check) {
+		return ErrUnsorted
+	}
 	return nil
 }
 
-func processPath(path string, config *Config) error {
+// processFiles processes files on a pool of workers and prints their output
+// in the order of files, as soon as all files before them are done
+func processFiles(files []string, config *Config) []error {
+	jobs := config.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]*fileResult, len(files))
+	done := make([]chan struct{}, len(files))
+	for i := range files {
+		results[i] = &fileResult{}
+		done[i] = make(chan struct{})
+	}
+
+	indexes := make(chan int)
+	go func() {
+		for i := range files {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for w := 0; w < min(jobs, len(files)); w++ {
+		go func() {
+			for i := range indexes {
+				results[i].err = processFile(files[i], config, results[i])
+				close(done[i])
+			}
+		}()
+	}
+
+	var errs []error
+	var writeErr error
+	for i, result := range results {
+		<-done[i]
+		// Once output fails, for example on a closed pipe, the rest of it
+		// is dropped rather than failing again for every file
+		if writeErr == nil {
+			if writeErr = result.write(os.Stdout, os.Stderr); writeErr != nil {
+				errs = append(errs, fmt.Errorf("writing output: %w", writeErr))
+			}
+		}
+		if result.unsorted {
+			config.unsorted = true
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+	}
+	return errs
+}
+
+// write prints what processing the file printed
+func (r *fileResult) write(stdout, stderr io.Writer) error {
+	if _, err := stdout.Write(r.stdout.Bytes()); err != nil {
+		return err
+	}
+	_, err := stderr.Write(r.stderr.Bytes())
+	return err
+}
+
+// withStrategy returns settings with the strategy named on the command line,
+// if any, after checking that the strategy in use is registered
+func withStrategy(settings *msortconfig.Config, strategy string) (*msortconfig.Config, error) {
+	if strategy != "" {
+		copied := *settings
+		copied.SortCriteria.Strategy = strategy
+		settings = &copied
+	}
+
+	if _, err := sorter.LookupStrategy(settings.SortCriteria.Strategy); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// collectFiles returns the files to process for a command line path, and
+// the errors that kept any of them from being found
+func collectFiles(path string, config *Config) ([]string, []error) {
+	if path == "-" {
+		return []string{path}, nil
+	}
+
 	info, err := os.Stat(path)
 	if err != nil {
-		return err
+		return nil, []error{fmt.Errorf("processing %s: %w", path, err)}
 	}
 
 	if info.IsDir() {
 		// Check if we're in a Go module context when processing directories
 		if err := checkGoModule(path); err != nil {
-			return err
+			return nil, []error{fmt.Errorf("processing %s: %w", path, err)}
 		}
-		return processDirectory(path, config)
+		return collectDirectory(path, config)
 	}
 
 	if strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
-		return processFile(path, config)
+		return []string{path}, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 func checkGoModule(dir string) error {
@@ -64,74 +242,279 @@ func checkGoModule(dir string) error {
 	return fmt.Errorf("go.mod file not found in current directory or any parent directory; see 'go help modules'")
 }
 
-func processDirectory(dir string, config *Config) error {
+// collectDirectory returns the files to process under dir. A directory that
+// cannot be read is reported as a ReadError, and the files found elsewhere
+// are still processed.
+func collectDirectory(dir string, config *Config) ([]string, []error) {
+	var errs []error
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return err
+		errs = append(errs, &FileError{Path: dir, Kind: ReadError, Err: err})
 	}
 
+	var files []string
 	for _, entry := range entries {
 		path := filepath.Join(dir, entry.Name())
 
 		if entry.IsDir() {
-			// Skip hidden directories (like go fmt)
-			if !strings.HasPrefix(entry.Name(), ".") {
-				if err := processDirectory(path, config); err != nil {
-					return err
-				}
+			// Skip hidden directories (like go fmt) and excluded ones
+			if !strings.HasPrefix(entry.Name(), ".") && !config.Settings.IsExcluded(path) {
+				found, dirErrs := collectDirectory(path, config)
+				files = append(files, found...)
+				errs = append(errs, dirErrs...)
 			}
 			continue
 		}
 
-		if strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
-			if err := processFile(path, config); err != nil {
-				return err
-			}
+		if strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") &&
+			config.Settings.ShouldProcess(path) {
+			files = append(files, path)
 		}
 	}
 
-	return nil
+	return files, errs
 }
 
-func processFile(filename string, config *Config) error {
+func processFile(filename string, config *Config, result *fileResult) error {
+	if filename == "-" {
+		return processStdin(config, result)
+	}
+
 	if config.Verbose {
-		fmt.Printf("Processing: %s\n", filename)
+		config.logf(result, "Processing: %s\n", filename)
 	}
 
 	// Read source file
 	source, err := os.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("reading %s: %w", filename, err)
+		return &FileError{Path: filename, Kind: ReadError, Err: err}
+	}
+
+	return processSource(filename, source, config, result)
+}
+
+func processStdin(config *Config, result *fileResult) error {
+	source, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return &FileError{Path: stdinName, Kind: ReadError, Err: err}
 	}
 
-	methodSorter, err := sorter.NewFromSource(string(source))
+	return processSource(stdinName, source, config, result)
+}
+
+func processSource(filename string, source []byte, config *Config, result *fileResult) error {
+	methodSorter, sorted, changed, err := sortSource(filename, source, config)
 	if err != nil {
-		return fmt.Errorf("parsing %s: %w", filename, err)
+		return err
 	}
+
+	if changed {
+		result.unsorted = true
+		if err := reportChange(filename, source, sorted, methodSorter.Moves(), config, result); err != nil {
+			return err
+		}
+	}
+
+	if config.List || config.Check || config.Diff {
+		return nil
+	}
+
+	return outputSource(filename, source, sorted, changed, config, result)
+}
+
+// sortSource sorts source and, if asked to, verifies the result
+func sortSource(filename string, source []byte, config *Config) (*sorter.Sorter, []byte, bool, error) {
+	methodSorter, err := newSorter(filename, source, config)
+	if err != nil {
+		return nil, nil, false, &FileError{Path: filename, Kind: ParseError, Err: err}
+	}
+	methodSorter.SetCriteria(config.Settings.SortCriteria)
+	methodSorter.SetDebug(config.Debug)
+
 	sorted, changed, err := methodSorter.Sort()
 	if err != nil {
-		return fmt.Errorf("sorting methods in %s: %w", filename, err)
+		return nil, nil, false, &FileError{Path: filename, Kind: SortError, Err: err}
+	}
+
+	if changed && config.Verify {
+		if err := sorter.Verify(source, sorted); err != nil {
+			return nil, nil, false, &FileError{Path: filename, Kind: VerifyError, Err: err}
+		}
+	}
+
+	return methodSorter, sorted, changed, nil
+}
+
+// reportChange lists or diffs a file that sorting changes
+func reportChange(filename string, source, sorted []byte, moves []sorter.Move, config *Config, result *fileResult) error {
+	if config.List {
+		fmt.Fprintln(&result.stdout, filename)
 	}
 
+	if config.Diff {
+		if err := printDiff(&result.stdout, filename, source, sorted, moves); err != nil {
+			return &FileError{Path: filename, Kind: WriteError, Err: err}
+		}
+	}
+	return nil
+}
+
+// outputSource writes the sorted source back to the file, or to stdout
+func outputSource(filename string, source, sorted []byte, changed bool, config *Config, result *fileResult) error {
 	if !changed {
 		if config.Verbose {
-			fmt.Printf("  No changes needed\n")
+			config.logf(result, "  No changes needed\n")
 		}
+		// Files that need no sorting are echoed untouched, not reformatted
+		sorted = source
+	}
+
+	if config.ToStdout || filename == stdinName {
+		result.stdout.Write(sorted)
+		return nil
+	}
+
+	if !changed {
 		return nil
 	}
 
 	if config.DryRun {
-		fmt.Printf("Would sort methods in: %s\n", filename)
+		fmt.Fprintf(&result.stdout, "Would sort methods in: %s\n", filename)
 		return nil
 	}
 
-	if err := sorter.WriteFile(filename, sorted); err != nil {
-		return fmt.Errorf("writing sorted file %s: %w", filename, err)
+	if err := sorter.WriteFileIfUnchanged(filename, source, sorted); err != nil {
+		return &FileError{Path: filename, Kind: WriteError, Err: err}
 	}
 
 	if config.Verbose {
-		fmt.Printf("  Methods sorted\n")
+		config.logf(result, "  Methods sorted\n")
 	}
 
 	return nil
 }
+
+// typeCheck reports whether calls are resolved with type information, as
+// asked for by -types or the configuration file
+func (c *Config) typeCheck() bool {
+	return c.TypeCheck || (c.Settings != nil && c.Settings.SortCriteria.TypeCheck)
+}
+
+// logf prints progress messages, keeping them off stdout when stdout
+// carries the sorted source
+func (c *Config) logf(result *fileResult, format string, args ...interface{}) {
+	if c.ToStdout || c.stdin {
+		fmt.Fprintf(&result.stderr, format, args...)
+		return
+	}
+	fmt.Fprintf(&result.stdout, format, args...)
+}
+
+func printDiff(w io.Writer, filename string, source, sorted []byte, moves []sorter.Move) error {
+	// A method-level summary first, so moves can be reviewed without the diff
+	fmt.Fprintf(w, "%s: %d methods moved\n", filename, len(moves))
+	for _, move := range moves {
+		name := move.Name
+		if move.ReceiverName != "" {
+			name = move.ReceiverName + "." + move.Name
+		}
+		fmt.Fprintf(w, "\t%s: %d -> %d\n", name, move.From, move.To)
+	}
+
+	_, err := w.Write(diff.Unified(filename+".orig", filename, source, sorted))
+	return err
+}
+
+func newSorter(filename string, source []byte, config *Config) (*sorter.Sorter, error) {
+	if (!config.typeCheck() && !config.Package) || filename == stdinName {
+		return sorter.NewFromSource(string(source))
+	}
+
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(absPath)
+
+	config.mu.Lock()
+	if config.dirSorters == nil {
+		config.dirSorters = make(map[string]*dirSorters)
+	}
+	entry, ok := config.dirSorters[dir]
+	if !ok {
+		entry = &dirSorters{}
+		config.dirSorters[dir] = entry
+	}
+	config.mu.Unlock()
+
+	// Workers on the same directory wait for one load
+	entry.once.Do(func() {
+		entry.sorters, entry.err = loadDir(dir, config)
+	})
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	// Sorters are single-use, and files outside the current build (for
+	// example other GOOS) are not part of the loaded package
+	config.mu.Lock()
+	methodSorter, ok := entry.sorters[absPath]
+	delete(entry.sorters, absPath)
+	config.mu.Unlock()
+	if ok {
+		return methodSorter, nil
+	}
+
+	return sorter.NewFromSource(string(source))
+}
+
+// loadPackages type-checks the packages of all files in one load, so that
+// their dependencies are checked once per run rather than once per directory
+func (c *Config) loadPackages(files []string) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, filename := range files {
+		if filename == "-" {
+			continue
+		}
+		// newSorter reports paths that cannot be made absolute
+		absPath, err := filepath.Abs(filename)
+		if err != nil {
+			continue
+		}
+		if dir := filepath.Dir(absPath); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	c.dirSorters = make(map[string]*dirSorters, len(dirs))
+	for dir, loaded := range sorter.LoadPackages(dirs) {
+		entry := &dirSorters{}
+		entry.once.Do(func() {
+			entry.sorters, entry.err = loaded.Sorters, loaded.Err
+			if entry.err == nil && c.Package {
+				sorter.LinkPackage(entry.sorters)
+			}
+		})
+		c.dirSorters[dir] = entry
+	}
+}
+
+// loadDir returns sorters for all files in dir, type-checked and linked into
+// one package as configured
+func loadDir(dir string, config *Config) (map[string]*sorter.Sorter, error) {
+	if !config.typeCheck() {
+		return sorter.ParseDir(dir, config.Settings)
+	}
+
+	sorters, err := sorter.LoadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	if config.Package {
+		sorter.LinkPackage(sorters)
+	}
+	return sorters, nil
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	msortconfig "github.com/borovikovd/gomsort/pkg/config"
+	"github.com/borovikovd/gomsort/pkg/sorter"
 )
 
 func TestRunWithDryRun(t *testing.T) {
@@ -620,3 +627,648 @@ func (s *Server incomplete syntax`
 		t.Error("Expected error from recursive directory processing")
 	}
 }
+
+func TestRunWithConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// With exported_first disabled the leaf helper (depth 0) sorts before Start (depth 1)
+	configPath := filepath.Join(tmpDir, "msort.json")
+	configContent := `{"sort_criteria": {"exported_first": false}}`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testFile := filepath.Join(tmpDir, "test.go")
+	testContent := `package test
+
+type Server struct{}
+
+func (s *Server) Start() error {
+	s.helper()
+	return nil
+}
+
+func (s *Server) helper() {}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Paths:      []string{testFile},
+		ConfigPath: configPath,
+	}
+
+	if err := Run(config); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startIndex := strings.Index(string(content), "func (s *Server) Start()")
+	helperIndex := strings.Index(string(content), "func (s *Server) helper()")
+	if helperIndex > startIndex {
+		t.Errorf("Expected helper before Start with exported_first disabled, got:\n%s", content)
+	}
+}
+
+func TestRunWithMissingConfigFile(t *testing.T) {
+	config := &Config{
+		Paths:      []string{"."},
+		ConfigPath: filepath.Join(t.TempDir(), "missing.json"),
+	}
+
+	if err := Run(config); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+}
+
+func TestRunWithExcludePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { return nil }
+`
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{
+		filepath.Join(tmpDir, "server.go"),
+		filepath.Join(tmpDir, "server_gen.go"),
+		filepath.Join(tmpDir, "vendor", "server.go"),
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte(unsorted), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := msortconfig.DefaultConfig()
+	settings.Exclude = []string{"*_gen.go", "vendor"}
+
+	config := &Config{
+		Paths:    []string{tmpDir},
+		Settings: settings,
+	}
+
+	if err := Run(config); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	expectations := map[string]bool{
+		files[0]: true,
+		files[1]: false,
+		files[2]: false,
+	}
+	for file, shouldChange := range expectations {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed := string(content) != unsorted; changed != shouldChange {
+			t.Errorf("%s: expected changed=%v, got %v", file, shouldChange, changed)
+		}
+	}
+}
+
+func TestRunWithTypeCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiver name srv is invisible to the heuristic call graph, so
+	// only the type-checked graph knows dial is a leaf and connect is not
+	testFile := filepath.Join(tmpDir, "server.go")
+	testContent := `package test
+
+type Server struct{}
+
+func (srv *Server) connect() {
+	srv.dial()
+}
+
+func (srv *Server) dial() {}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(&Config{Paths: []string{tmpDir}}); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != testContent {
+		t.Fatalf("Expected heuristic run to leave file unchanged, got:\n%s", content)
+	}
+
+	if err := Run(&Config{Paths: []string{tmpDir}, TypeCheck: true}); err != nil {
+		t.Fatalf("Run() with TypeCheck failed: %v", err)
+	}
+
+	content, err = os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(content), "func (srv *Server) dial()") > strings.Index(string(content), "func (srv *Server) connect()") {
+		t.Errorf("Expected dial before connect with TypeCheck, got:\n%s", content)
+	}
+}
+
+func TestRunWithCheckAndList(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { return nil }
+`
+	sorted := `package test
+
+type Client struct{}
+
+func (c *Client) Connect() error { return nil }
+`
+
+	unsortedFile := filepath.Join(tmpDir, "server.go")
+	sortedFile := filepath.Join(tmpDir, "client.go")
+	if err := os.WriteFile(unsortedFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sortedFile, []byte(sorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, config := range []*Config{
+		{Check: true, Paths: []string{tmpDir}},
+		{List: true, Paths: []string{tmpDir}},
+	} {
+		if err := Run(config); !errors.Is(err, ErrUnsorted) {
+			t.Errorf("Expected ErrUnsorted, got %v", err)
+		}
+
+		content, err := os.ReadFile(unsortedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != unsorted {
+			t.Error("File was modified in check/list mode")
+		}
+
+		// Only sorted files left: clean exit
+		config.Paths = []string{sortedFile}
+		if err := Run(config); err != nil {
+			t.Errorf("Expected no error for sorted file, got %v", err)
+		}
+	}
+}
+
+// sortedServer is the output for the Server sources of the stdout tests
+const sortedServer = `package test
+
+type Server struct{}
+
+func (s *Server) Start() error { return nil }
+
+func (s *Server) helper() {}
+`
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Read while fn writes, so a full pipe does not block it
+	output := make(chan string)
+	go func() {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Error(err)
+		}
+		output <- string(data)
+	}()
+
+	origStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = origStdout }()
+
+	fn()
+	os.Stdout = origStdout
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return <-output
+}
+
+func TestRunWithStdin(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { return nil }
+`
+
+	stdinFile := filepath.Join(t.TempDir(), "stdin.go")
+	if err := os.WriteFile(stdinFile, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdin, err := os.Open(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	origStdin := os.Stdin
+	defer func() { os.Stdin = origStdin }()
+	os.Stdin = stdin
+
+	// Type checking does not apply to stdin, which has no package
+	config := &Config{Paths: []string{"-"}, TypeCheck: true}
+	var runErr error
+	output := captureStdout(t, func() { runErr = Run(config) })
+	if runErr != nil {
+		t.Errorf("Expected no error reading stdin, got %v", runErr)
+	}
+	if output != sortedServer {
+		t.Errorf("Expected sorted source on stdout, got:\n%s", output)
+	}
+}
+
+func TestRunWithToStdout(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "server.go")
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { return nil }
+`
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{ToStdout: true, Paths: []string{testFile}}
+	var runErr error
+	output := captureStdout(t, func() { runErr = Run(config) })
+	if runErr != nil {
+		t.Errorf("Expected no error, got %v", runErr)
+	}
+	if output != sortedServer {
+		t.Errorf("Expected sorted source on stdout, got:\n%s", output)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != unsorted {
+		t.Error("File was modified when writing to stdout")
+	}
+}
+
+func TestRunWithPackage(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// helper is only called from the other file, so only package mode sees
+	// its in-degree
+	serverFile := filepath.Join(tmpDir, "server.go")
+	serverContent := `package test
+
+type Server struct{}
+
+func (s *Server) close() {}
+
+func (s *Server) helper() {}
+`
+	if err := os.WriteFile(serverFile, []byte(serverContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	httpContent := `package test
+
+func (s *Server) handle() {
+	s.helper()
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "server_http.go"), []byte(httpContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(&Config{Check: true, Paths: []string{tmpDir}}); err != nil {
+		t.Fatalf("Expected files to look sorted one at a time, got %v", err)
+	}
+
+	if err := Run(&Config{Check: true, Package: true, Paths: []string{serverFile}}); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("Expected ErrUnsorted with package metrics, got %v", err)
+	}
+
+	if err := Run(&Config{Package: true, TypeCheck: true, Paths: []string{tmpDir}}); err != nil {
+		t.Fatalf("Run() with Package and TypeCheck failed: %v", err)
+	}
+
+	content, err := os.ReadFile(serverFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(content), "func (s *Server) helper()") > strings.Index(string(content), "func (s *Server) close()") {
+		t.Errorf("Expected helper to move with package metrics, got:\n%s", content)
+	}
+}
+
+func TestRunWithPackageAndBrokenFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sorted := `package test
+
+type Server struct{}
+
+func (s *Server) Start() error { return nil }
+`
+	for name, content := range map[string]string{
+		"go.mod":       "module testmodule\n\ngo 1.22\n",
+		"a.go":         sorted,
+		"b.go":         "package test\n\nfunc (s *Server) Stop() {}\n",
+		"zz_broken.go": "package test\n\nfunc broken( {\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the broken file fails; the others are still linked and checked
+	err := Run(&Config{Check: true, Package: true, Paths: []string{tmpDir}})
+	fileErrors := FileErrors(err)
+	if len(fileErrors) != 1 || fileErrors[0].Kind != ParseError || filepath.Base(fileErrors[0].Path) != "zz_broken.go" {
+		t.Errorf("Expected one parse error for zz_broken.go, got %v", err)
+	}
+}
+
+func TestRunWithTypeCheckSetting(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Only the type checker sees that Start calls b, since the receiver is
+	// not named after its type
+	source := `package test
+
+type Server struct{}
+
+func (srv *Server) Start() {
+	srv.b()
+}
+
+func (srv *Server) a() {}
+
+func (srv *Server) b() {}
+`
+	for name, content := range map[string]string{
+		"go.mod":    "module testmodule\n\ngo 1.22\n",
+		"server.go": source,
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Run(&Config{Check: true, Paths: []string{tmpDir}}); err != nil {
+		t.Fatalf("Expected the file to look sorted without type information, got %v", err)
+	}
+
+	settings := msortconfig.DefaultConfig()
+	settings.SortCriteria.TypeCheck = true
+	if err := Run(&Config{Check: true, Settings: settings, Paths: []string{tmpDir}}); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("Expected type_check in the settings to act as -types, got %v", err)
+	}
+}
+
+func TestRunWithJobsProcessesEveryFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { return nil }
+`
+	var files []string
+	for i := 0; i < 20; i++ {
+		file := filepath.Join(tmpDir, fmt.Sprintf("server%02d.go", i))
+		if err := os.WriteFile(file, []byte(unsorted), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	brokenFiles := []string{filepath.Join(tmpDir, "broken1.go"), filepath.Join(tmpDir, "broken2.go")}
+	for _, file := range brokenFiles {
+		if err := os.WriteFile(file, []byte("package test\n\nfunc broken( {\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := Run(&Config{Jobs: 4, Paths: []string{tmpDir}})
+	if err == nil {
+		t.Fatal("Expected errors for the broken files")
+	}
+	for _, file := range brokenFiles {
+		if !strings.Contains(err.Error(), file) {
+			t.Errorf("Expected error to mention %s, got: %v", file, err)
+		}
+	}
+
+	// A failing file does not stop the others
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) == unsorted {
+			t.Errorf("Expected %s to be sorted", file)
+		}
+	}
+}
+
+func TestRunWithVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "server.go")
+
+	unsorted := `//go:build linux
+
+package test
+
+import "fmt"
+
+type Server struct{}
+
+// helper prints
+func (s *Server) helper() { fmt.Println("help") }
+
+func (s *Server) Start() error {
+	s.helper() // the only call
+	return nil
+}
+`
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(&Config{Verify: true, Paths: []string{testFile}}); err != nil {
+		t.Fatalf("Expected verified sort to succeed, got %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(content), "Start") > strings.Index(string(content), "helper()") {
+		t.Errorf("Expected the file to be sorted, got:\n%s", content)
+	}
+}
+
+func TestRunWithDebug(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "server.go")
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) Start() error { s.helper(); return nil }
+func NewServer() *Server       { return &Server{} }
+`
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(&Config{Debug: true, Paths: []string{testFile}}); err != nil {
+		t.Fatalf("Expected sorting to be idempotent, got %v", err)
+	}
+
+	// A second run has nothing left to do
+	if err := Run(&Config{Check: true, Paths: []string{testFile}}); err != nil {
+		t.Errorf("Expected the sorted file to pass -check, got %v", err)
+	}
+}
+
+func TestRunWithStrategy(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "server.go")
+
+	// By depth b, a leaf, would follow a; in call order c follows a
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) c() {}
+func (s *Server) b() {}
+func (s *Server) a() { s.c() }
+func (s *Server) Start() { s.a(); s.b() }
+`
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := msortconfig.DefaultConfig()
+	if err := Run(&Config{Strategy: msortconfig.StrategyStepdown, Settings: settings, Paths: []string{testFile}}); err != nil {
+		t.Fatal(err)
+	}
+	if settings.SortCriteria.Strategy != msortconfig.StrategyDepth {
+		t.Errorf("Expected the caller's settings to be left alone, got strategy %q", settings.SortCriteria.Strategy)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := -1
+	for _, name := range []string{"Start", "a", "c", "b"} {
+		index := strings.Index(string(content), "func (s *Server) "+name+"(")
+		if index < previous {
+			t.Fatalf("Expected Start, a, c, b in call order, got:\n%s", content)
+		}
+		previous = index
+	}
+
+	err = Run(&Config{Strategy: "alphabetical", Paths: []string{testFile}})
+	if !errors.Is(err, sorter.ErrUnknownStrategy) {
+		t.Errorf("Expected an unknown strategy error, got %v", err)
+	}
+}
+
+// failingWriter fails every write, like a closed pipe
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestFileResultWriteErrors(t *testing.T) {
+	result := &fileResult{}
+	result.stdout.WriteString("server.go\n")
+	result.stderr.WriteString("Processing: server.go\n")
+
+	var stdout, stderr bytes.Buffer
+	if err := result.write(&stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "server.go\n" || stderr.String() != "Processing: server.go\n" {
+		t.Errorf("Unexpected output %q, %q", stdout.String(), stderr.String())
+	}
+
+	if err := result.write(failingWriter{}, &stderr); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the stdout error, got %v", err)
+	}
+	if err := result.write(&stdout, failingWriter{}); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the stderr error, got %v", err)
+	}
+
+	err := printDiff(failingWriter{}, "server.go", []byte("a\n"), []byte("b\n"), nil)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected printDiff to return the write error, got %v", err)
+	}
+}
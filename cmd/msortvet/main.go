@@ -0,0 +1,15 @@
+// Command msortvet runs the msort analyzer on its own or as a vet tool:
+//
+//	msortvet ./...
+//	go vet -vettool=$(which msortvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/borovikovd/gomsort/pkg/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
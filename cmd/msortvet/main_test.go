@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMsortvetBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "msortvet")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	moduleDir := filepath.Join(tmpDir, "module")
+	if err := os.Mkdir(moduleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testContent := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() {
+	s.helper()
+}
+`
+	testFile := filepath.Join(moduleDir, "server.go")
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+		output   string
+	}{
+		{"report", []string{"./..."}, 3, "server.go:7:18: exported method Start should precede unexported helper"},
+		{"flags", []string{"-exported_first=false", "./..."}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tt.args...)
+			cmd.Dir = moduleDir
+			output, err := cmd.CombinedOutput()
+
+			exitCode := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Binary execution failed: %v", err)
+			}
+
+			if exitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d\nOutput: %s", tt.exitCode, exitCode, output)
+			}
+			if !strings.Contains(string(output), tt.output) {
+				t.Errorf("Expected output containing %q, got: %s", tt.output, output)
+			}
+		})
+	}
+}
@@ -1,47 +1,252 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
+	"runtime"
+	"strings"
 
 	"github.com/borovikovd/gomsort/cmd"
+	"github.com/borovikovd/gomsort/pkg/sorter"
 )
 
+// command is a gomsort subcommand; run returns the exit status
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the subcommands. Arguments that do not start with one are
+// passed to sort, as before subcommands existed. The list is set in init
+// because the usage of sort prints it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"sort", "sort methods in files and directories (the default)", runSort},
+		{"check", "list files that are not sorted, exiting 1 if there are any", runCheck},
+		{"graph", "print the method call graph", runGraph},
+		{"explain", "show the order methods are sorted into and why", runExplain},
+		{"init", "write a default " + cmd.DefaultConfigFile, runInit},
+	}
+}
+
 func main() {
-	var (
-		dryRun  = flag.Bool("n", false, "dry run - show what would be changed without modifying files")
-		verbose = flag.Bool("v", false, "verbose output")
-	)
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [files/directories...]\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "\ngo-msort sorts Go methods within types for better readability.\n")
-		fmt.Fprintf(os.Stderr, "Recursively processes directories like 'go fmt'.\n")
-		fmt.Fprintf(os.Stderr, "Methods are sorted by:\n")
-		fmt.Fprintf(os.Stderr, "  1. Receiver type (grouped together)\n")
-		fmt.Fprintf(os.Stderr, "  2. Exported methods first\n")
-		fmt.Fprintf(os.Stderr, "  3. Entry points (low call depth) first\n")
-		fmt.Fprintf(os.Stderr, "  4. Helper methods (high in-degree) last\n")
-		fmt.Fprintf(os.Stderr, "\nOptions:\n")
-		flag.PrintDefaults()
+	args := os.Args[1:]
+	run := runSort
+	if len(args) > 0 {
+		for _, c := range commands {
+			if args[0] == c.name {
+				run, args = c.run, args[1:]
+				break
+			}
+		}
 	}
 
-	flag.Parse()
+	if code := run(args); code != 0 {
+		os.Exit(code)
+	}
+}
+
+func runSort(args []string) int {
+	return sortCommand("sort", args, false)
+}
+
+func runCheck(args []string) int {
+	return sortCommand("check", args, true)
+}
+
+// strategyUsage documents the -strategy flag with the registered strategies
+func strategyUsage() string {
+	return "how to order each type's methods: " + strings.Join(sorter.Strategies(), ", ") +
+		" (default: from the configuration file)"
+}
+
+// sortCommand runs sort or, with check, check; they share most options
+func sortCommand(name string, args []string, check bool) int {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	config := &cmd.Config{}
+	write := true
 
-	args := flag.Args()
-	if len(args) == 0 {
-		args = []string{"."}
+	if check {
+		config.Check, config.List = true, true
+		flags.BoolVar(&config.Diff, "d", false, "also print a diff for each file that is not sorted")
+	} else {
+		flags.BoolVar(&config.DryRun, "n", false, "dry run - show what would be changed without modifying files")
+		flags.BoolVar(&config.Diff, "d", false, "display diffs instead of rewriting files")
+		flags.BoolVar(&config.List, "l", false, "list files whose methods are not sorted instead of rewriting them")
+		flags.BoolVar(&config.Check, "check", false, "do not rewrite files; exit with status 1 if any file is not sorted")
+		flags.BoolVar(&write, "w", true, "write results to the source files; with -w=false print them to stdout")
+		flags.BoolVar(&config.Verify, "verify", false, "check that sorting only reordered declarations before using the result")
+		flags.BoolVar(&config.Debug, "debug", false, "check that sorting each result again changes nothing")
 	}
+	flags.BoolVar(&config.Verbose, "v", false, "verbose output")
+	flags.StringVar(&config.ConfigPath, "config", "", "path to configuration file (default: discovered .msort.json)")
+	flags.BoolVar(&config.TypeCheck, "types", false, "resolve method calls with type information (slower, exact call graph)")
+	flags.BoolVar(&config.Package, "pkg", false, "compute call depth and in-degree across all files of each package")
+	flags.StringVar(&config.Strategy, "strategy", "", strategyUsage())
+	flags.IntVar(&config.Jobs, "j", runtime.GOMAXPROCS(0), "number of files to process in parallel")
+
+	flags.Usage = func() {
+		if check {
+			fmt.Fprintf(os.Stderr, "Usage: %s check [options] [files/directories...]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "\nLists the files whose methods are not sorted, without changing them.\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Usage: %s [sort] [options] [files/directories...]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s [sort] [options] < file.go\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s <command> [options] [arguments...]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "\ngo-msort sorts Go methods within types for better readability.\n")
+			fmt.Fprintf(os.Stderr, "Recursively processes directories like 'go fmt'.\n")
+			fmt.Fprintf(os.Stderr, "With no paths and piped input, or with the path '-', reads source from\n")
+			fmt.Fprintf(os.Stderr, "stdin and writes the result to stdout.\n")
+			fmt.Fprintf(os.Stderr, "Methods are sorted by:\n")
+			fmt.Fprintf(os.Stderr, "  1. Receiver type (grouped together)\n")
+			fmt.Fprintf(os.Stderr, "  2. Exported methods first\n")
+			fmt.Fprintf(os.Stderr, "  3. Entry points (low call depth) first\n")
+			fmt.Fprintf(os.Stderr, "  4. Helper methods (high in-degree) last\n")
+			fmt.Fprintf(os.Stderr, "\nCommands:\n")
+			for _, c := range commands {
+				fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+			}
+			fmt.Fprintf(os.Stderr, "Run '%s <command> -h' for the options of a command.\n", os.Args[0])
+		}
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExit status:\n")
+		fmt.Fprintf(os.Stderr, "  0  success (with -l/-check: all files are sorted)\n")
+		fmt.Fprintf(os.Stderr, "  1  with -l/-check: at least one file is not sorted\n")
+		fmt.Fprintf(os.Stderr, "  2  an error occurred\n")
+	}
+
+	flags.Parse(args)
+	config.ToStdout = !write
 
-	config := &cmd.Config{
-		DryRun:  *dryRun,
-		Verbose: *verbose,
-		Paths:   args,
+	config.Paths = flags.Args()
+	if len(config.Paths) == 0 {
+		config.Paths = []string{"."}
+		if !check && stdinIsPiped() {
+			config.Paths = []string{"-"}
+		}
 	}
 
 	if err := cmd.Run(config); err != nil {
-		log.Fatal(err)
+		if errors.Is(err, cmd.ErrUnsorted) {
+			return 1
+		}
+		cmd.WriteSummary(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runExplain implements "gomsort explain", which shows the order methods are
+// sorted into and the rule that places each of them
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	config := &cmd.ExplainConfig{}
+	flags.StringVar(&config.Format, "format", cmd.FormatText, "output format: text or json")
+	flags.StringVar(&config.ConfigPath, "config", "", "path to configuration file (default: discovered .msort.json)")
+	flags.BoolVar(&config.TypeCheck, "types", false, "resolve method calls with type information (slower, exact call graph)")
+	flags.BoolVar(&config.Package, "pkg", false, "compute call depth and in-degree across all files of each package")
+	flags.StringVar(&config.Strategy, "strategy", "", strategyUsage())
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s explain [options] file.go[:Type]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nShows, per receiver type, the order methods are sorted into: each method's\n")
+		fmt.Fprintf(os.Stderr, "visibility, call depth, in-degree and original position, and the rule that\n")
+		fmt.Fprintf(os.Stderr, "puts it after the method before it. With :Type, only that type is shown.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	config.Paths = flags.Args()
+
+	if err := cmd.Explain(os.Stdout, config); err != nil {
+		fmt.Fprintf(os.Stderr, "%s explain: %v\n", os.Args[0], err)
+		return 2
+	}
+	return 0
+}
+
+// runInit implements "gomsort init", which writes the default configuration
+func runInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	path := flags.String("config", cmd.DefaultConfigFile, "path of the configuration file to write")
+	force := flags.Bool("force", false, "overwrite an existing file")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s init [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrites the default configuration, to be edited from there.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := cmd.InitConfig(*path, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "%s init: %v\n", os.Args[0], err)
+		return 2
+	}
+	fmt.Printf("Wrote %s\n", *path)
+	return 0
+}
+
+// runGraph implements "gomsort graph", which prints the method call graph of
+// a file or package
+func runGraph(args []string) int {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := flags.String("format", cmd.FormatDOT, "output format: dot, mermaid or json")
+	typeName := flags.String("type", "", "only show the methods of this receiver type")
+	typeCheck := flags.Bool("types", false, "resolve method calls with type information (slower, exact call graph)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s graph [options] [file|directory]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrints the method call graph of a file, or of the package in a directory\n")
+		fmt.Fprintf(os.Stderr, "(default: the current one), with the in-degree and call depth of each method.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	path := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		path = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	config := &cmd.GraphConfig{
+		Path:      path,
+		Format:    *format,
+		TypeCheck: *typeCheck,
+		Type:      *typeName,
+	}
+	if err := cmd.Graph(os.Stdout, config); err != nil {
+		fmt.Fprintf(os.Stderr, "%s graph: %v\n", os.Args[0], err)
+		return 2
+	}
+	return 0
+}
+
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice == 0
 }
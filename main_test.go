@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -296,9 +297,19 @@ func (s *Server) Start() error { return nil }
 	origArgs := os.Args
 	defer func() { os.Args = origArgs }()
 
-	// Test with no arguments (should default to ".")
+	// Test with no arguments (should default to "."), which needs stdin to
+	// not look piped
 	os.Args = []string{"gomsort"}
 
+	origStdin := os.Stdin
+	defer func() { os.Stdin = origStdin }()
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdin = devNull
+
 	// Reset flag package state
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
@@ -430,3 +441,416 @@ func (s *Server) Start() error { return nil }
 
 	main()
 }
+
+func TestMainBinaryWithDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	testFile := filepath.Join(tmpDir, "test.go")
+	testContent := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd = exec.Command(binaryPath, "-d", testFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Binary execution failed: %v\nOutput: %s", err, output)
+	}
+
+	expected := testFile + ": 2 methods moved\n" +
+		"\tServer.helper: 0 -> 1\n" +
+		"\tServer.Start: 1 -> 0\n" +
+		"--- " + testFile + ".orig\n" +
+		"+++ " + testFile + "\n" +
+		"@@ -2,6 +2,6 @@\n" +
+		" \n" +
+		" type Server struct{}\n" +
+		" \n" +
+		"-func (s *Server) helper() {}\n" +
+		"-\n" +
+		" func (s *Server) Start() error { return nil }\n" +
+		"+\n" +
+		"+func (s *Server) helper() {}\n"
+
+	if string(output) != expected {
+		t.Errorf("Unexpected diff output.\nExpected:\n%s\nGot:\n%s", expected, output)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != testContent {
+		t.Error("File was modified in diff mode")
+	}
+}
+
+func TestMainBinaryExitCodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	unsortedFile := filepath.Join(tmpDir, "server.go")
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	if err := os.WriteFile(unsortedFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sortedFile := filepath.Join(tmpDir, "client.go")
+	if err := os.WriteFile(sortedFile, []byte("package test\n\ntype Client struct{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+		output   string
+	}{
+		{"list unsorted", []string{"-l", unsortedFile, sortedFile}, 1, unsortedFile + "\n"},
+		{"list sorted", []string{"-l", sortedFile}, 0, ""},
+		{"check unsorted", []string{"-check", unsortedFile}, 1, ""},
+		{"check sorted", []string{"-check", sortedFile}, 0, ""},
+		{"error", []string{"-check", filepath.Join(tmpDir, "missing.go")}, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tt.args...)
+			output, err := cmd.Output()
+
+			exitCode := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Binary execution failed: %v", err)
+			}
+
+			if exitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, exitCode)
+			}
+			if string(output) != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, output)
+			}
+		})
+	}
+}
+
+func TestMainBinaryStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	expected := `package test
+
+type Server struct{}
+
+func (s *Server) Start() error { return nil }
+
+func (s *Server) helper() {}
+`
+
+	for _, args := range [][]string{nil, {"-"}} {
+		cmd = exec.Command(binaryPath, args...)
+		cmd.Stdin = strings.NewReader(unsorted)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Binary execution with args %q failed: %v", args, err)
+		}
+		if string(output) != expected {
+			t.Errorf("Args %q: expected output:\n%s\ngot:\n%s", args, expected, output)
+		}
+	}
+}
+
+func TestMainBinaryNoWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	testFile := filepath.Join(tmpDir, "server.go")
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd = exec.Command(binaryPath, "-w=false", testFile)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Binary execution failed: %v", err)
+	}
+
+	if !strings.Contains(string(output), "func (s *Server) Start() error { return nil }\n\nfunc (s *Server) helper() {}") {
+		t.Errorf("Expected sorted source on stdout, got:\n%s", output)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != unsorted {
+		t.Error("File was modified with -w=false")
+	}
+}
+
+func TestMainBinaryParallelOutputOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	moduleDir := filepath.Join(tmpDir, "module")
+	if err := os.Mkdir(moduleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	var expected strings.Builder
+	for i := 0; i < 30; i++ {
+		file := filepath.Join(moduleDir, fmt.Sprintf("server%02d.go", i))
+		if err := os.WriteFile(file, []byte(unsorted), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expected.WriteString(file + "\n")
+	}
+
+	cmd = exec.Command(binaryPath, "-l", "-j", "8", moduleDir)
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit code 1, got %v", err)
+	}
+
+	if string(output) != expected.String() {
+		t.Errorf("Expected files listed in order:\n%s\ngot:\n%s", expected.String(), output)
+	}
+}
+
+func TestMainBinaryErrorSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	brokenFile := filepath.Join(tmpDir, "broken.go")
+	if err := os.WriteFile(brokenFile, []byte("package test\n\nfunc broken( {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	sortedFile := filepath.Join(tmpDir, "server.go")
+	if err := os.WriteFile(sortedFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr strings.Builder
+	cmd = exec.Command(binaryPath, brokenFile, sortedFile)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit code 2, got %v", err)
+	}
+
+	if !strings.HasPrefix(stderr.String(), "1 file failed:\n  "+brokenFile+": parse error: ") {
+		t.Errorf("Unexpected summary:\n%s", stderr.String())
+	}
+
+	// The broken file did not stop the other one
+	content, err := os.ReadFile(sortedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) == unsorted {
+		t.Error("Expected the valid file to be sorted")
+	}
+}
+
+func TestMainBinaryGraph(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	testFile := filepath.Join(tmpDir, "server.go")
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) Start() { s.connect() }
+func (s *Server) connect() {}
+`
+	if err := os.WriteFile(testFile, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(binaryPath, "graph", "-format", "mermaid", testFile).Output()
+	if err != nil {
+		t.Fatalf("graph failed: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "flowchart TD") || !strings.Contains(string(output), "m0 --> m1") {
+		t.Errorf("Unexpected graph:\n%s", output)
+	}
+
+	// The graph command never rewrites the file
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != source {
+		t.Error("graph modified the file")
+	}
+
+	cmd = exec.Command(binaryPath, "graph", "-format", "svg", testFile)
+	if err := cmd.Run(); err == nil {
+		t.Error("Expected an unknown format to fail")
+	} else if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Errorf("Expected exit status 2, got %v", err)
+	}
+}
+
+func TestMainBinarySubcommands(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := filepath.Join(tmpDir, "gomsort")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	workDir := filepath.Join(tmpDir, "work")
+	if err := os.Mkdir(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() error { return nil }
+`
+	testFile := filepath.Join(workDir, "server.go")
+	if err := os.WriteFile(testFile, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, int) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Dir = workDir
+		output, err := cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return string(output), exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		return string(output), 0
+	}
+
+	if output, code := run("check"); code != 1 || output != "server.go\n" {
+		t.Errorf("check: expected server.go and exit 1, got %q and %d", output, code)
+	}
+
+	if output, code := run("explain", "server.go"); code != 0 ||
+		!strings.Contains(output, "server.go:7:18: exported method Start should precede unexported helper") {
+		t.Errorf("explain: unexpected output %q (exit %d)", output, code)
+	}
+
+	// Paths without a command are sorted, as before
+	if output, code := run("-n", "server.go"); code != 0 || !strings.Contains(output, "Would sort methods in: server.go") {
+		t.Errorf("bare paths: unexpected output %q (exit %d)", output, code)
+	}
+
+	if _, code := run("sort", "server.go"); code != 0 {
+		t.Errorf("sort: expected exit 0, got %d", code)
+	}
+	if output, code := run("check"); code != 0 || output != "" {
+		t.Errorf("check after sort: expected no output and exit 0, got %q and %d", output, code)
+	}
+
+	if _, code := run("init"); code != 0 {
+		t.Errorf("init: expected exit 0, got %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, ".msort.json")); err != nil {
+		t.Errorf("init did not write .msort.json: %v", err)
+	}
+	if output, code := run("init"); code != 2 || !strings.Contains(output, "already exists") {
+		t.Errorf("second init: expected exit 2, got %q and %d", output, code)
+	}
+}
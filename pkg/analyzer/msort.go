@@ -1,14 +1,19 @@
 package analyzer
 
 import (
-	"bytes"
+	"fmt"
 	"go/ast"
-	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
 
+	"github.com/borovikovd/gomsort/pkg/config"
+	"github.com/borovikovd/gomsort/pkg/diff"
 	"github.com/borovikovd/gomsort/pkg/sorter"
 )
 
@@ -19,6 +24,156 @@ var Analyzer = &analysis.Analyzer{
 	Requires: []*analysis.Analyzer{inspect.Analyzer},
 }
 
+// Flags mirror the configuration file, and only flags that are set override
+// it. Drivers register the flag values in flag sets of their own, so each
+// value records being set itself; Analyzer.Flags.Visit would never see it.
+var configPath string
+
+type settingFlag struct {
+	name     string
+	usage    string
+	defValue string
+	isBool   bool
+	apply    func(settings *config.Config, value string) error
+
+	value string
+	set   bool
+}
+
+var settingFlags = []*settingFlag{
+	criterionFlag("group_by_receiver", "group methods by receiver type",
+		func(c *config.SortCriteria) *bool { return &c.GroupByReceiver }),
+	criterionFlag("exported_first", "put exported methods before unexported ones",
+		func(c *config.SortCriteria) *bool { return &c.ExportedFirst }),
+	criterionFlag("sort_by_depth", "order methods by call depth",
+		func(c *config.SortCriteria) *bool { return &c.SortByDepth }),
+	criterionFlag("sort_by_in_degree", "order methods by in-degree",
+		func(c *config.SortCriteria) *bool { return &c.SortByInDegree }),
+	criterionFlag("preserve_original_order", "fall back to the original order for ties",
+		func(c *config.SortCriteria) *bool { return &c.PreserveOrigOrder }),
+	criterionFlag("keep_constructors", "keep NewX constructors next to their type's methods",
+		func(c *config.SortCriteria) *bool { return &c.KeepConstructors }),
+	criterionFlag("strict_comments", "skip files where a moved comment may belong to several declarations",
+		func(c *config.SortCriteria) *bool { return &c.StrictComments }),
+	criterionFlag("type_check", "resolve method calls with type information, as the CLI's -types",
+		func(c *config.SortCriteria) *bool { return &c.TypeCheck }),
+	{
+		name:     "strategy",
+		usage:    "how to order each type's methods: depth, stepdown or a registered strategy",
+		defValue: config.StrategyDepth,
+		apply: func(settings *config.Config, value string) error {
+			settings.SortCriteria.Strategy = value
+			return nil
+		},
+	},
+	{
+		name:     "layout",
+		usage:    "where to place sorted methods: end or after_type",
+		defValue: config.LayoutEnd,
+		apply: func(settings *config.Config, value string) error {
+			settings.SortCriteria.Layout = value
+			return nil
+		},
+	},
+	{
+		name:     "include",
+		usage:    "comma-separated glob patterns of files to check",
+		defValue: strings.Join(config.DefaultConfig().Include, ","),
+		apply: func(settings *config.Config, value string) error {
+			settings.Include = splitPatterns(value)
+			return nil
+		},
+	},
+	{
+		name:  "exclude",
+		usage: "comma-separated glob patterns of files to skip",
+		apply: func(settings *config.Config, value string) error {
+			settings.Exclude = splitPatterns(value)
+			return nil
+		},
+	},
+}
+
+func criterionFlag(name, usage string, field func(*config.SortCriteria) *bool) *settingFlag {
+	defaults := config.DefaultConfig().SortCriteria
+	return &settingFlag{
+		name:     name,
+		usage:    usage,
+		defValue: strconv.FormatBool(*field(&defaults)),
+		isBool:   true,
+		apply: func(settings *config.Config, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			*field(&settings.SortCriteria) = enabled
+			return nil
+		},
+	}
+}
+
+func (f *settingFlag) String() string {
+	if f.set {
+		return f.value
+	}
+	return f.defValue
+}
+
+func (f *settingFlag) Set(value string) error {
+	if f.isBool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return err
+		}
+	}
+	f.value, f.set = value, true
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func init() {
+	Analyzer.Flags.StringVar(&configPath, "config", "", "path to configuration file (default: discovered .msort.json)")
+	for _, setting := range settingFlags {
+		Analyzer.Flags.Var(setting, setting.name, setting.usage)
+	}
+}
+
+func loadSettings() (*config.Config, error) {
+	settings, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, setting := range settingFlags {
+		if !setting.set {
+			continue
+		}
+		if err := setting.apply(settings, setting.value); err != nil {
+			return nil, fmt.Errorf("-%s: %w", setting.name, err)
+		}
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := sorter.LookupStrategy(settings.SortCriteria.Strategy); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
 func run(pass *analysis.Pass) (interface{}, error) {
 	if pass == nil {
 		return nil, nil
@@ -29,6 +184,11 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		return nil, nil
 	}
 
+	settings, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+
 	nodeFilter := []ast.Node{
 		(*ast.File)(nil),
 	}
@@ -39,27 +199,102 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			return
 		}
 
-		// Convert AST to source code
-		var buf bytes.Buffer
-		if err := format.Node(&buf, pass.Fset, file); err != nil {
+		tokFile := pass.Fset.File(file.Pos())
+		if tokFile == nil || !settings.ShouldProcess(tokFile.Name()) {
 			return
 		}
 
-		// Use DST-based sorter
-		methodSorter, err := sorter.NewFromSource(buf.String())
+		// Sort the file as parsed, so positions refer to it. Calls are
+		// only resolved with the driver's type information if asked to, so
+		// that the analyzer sorts as the CLI does.
+		var info *types.Info
+		if settings.SortCriteria.TypeCheck {
+			info = pass.TypesInfo
+		}
+		methodSorter, err := sorter.NewFromFile(pass.Fset, file, info)
 		if err != nil {
 			return
 		}
+		methodSorter.SetCriteria(settings.SortCriteria)
 
-		_, changed, err := methodSorter.Sort()
+		sorted, changed, err := methodSorter.Sort()
 		if err != nil {
 			return
 		}
 
-		if changed {
-			pass.Reportf(file.Pos(), "methods in this file could be better sorted for readability")
+		if !changed {
+			return
+		}
+
+		// Fixes can only be computed against the bytes the file was parsed
+		// from. Every diagnostic offers the same fix, so any of them sorts
+		// the file.
+		var fixes []analysis.SuggestedFix
+		if source, ok := originalSource(pass, tokFile); ok {
+			fixes = []analysis.SuggestedFix{{
+				Message:   "Sort methods",
+				TextEdits: textEdits(tokFile, source, sorted),
+			}}
+		}
+
+		reported := false
+		for _, misplaced := range methodSorter.Misplaced() {
+			if !misplaced.Pos.IsValid() {
+				continue
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:            misplaced.Pos,
+				End:            misplaced.Pos + token.Pos(len(misplaced.Name)),
+				Message:        misplaced.Message,
+				SuggestedFixes: fixes,
+			})
+			reported = true
+		}
+
+		// Only constructors moved
+		if !reported {
+			pass.Report(analysis.Diagnostic{
+				Pos:            file.Pos(),
+				Message:        "methods in this file could be better sorted for readability",
+				SuggestedFixes: fixes,
+			})
 		}
 	})
 
 	return nil, nil
 }
+
+func originalSource(pass *analysis.Pass, tokFile *token.File) ([]byte, bool) {
+	if pass.ReadFile == nil || tokFile == nil {
+		return nil, false
+	}
+
+	source, err := pass.ReadFile(tokFile.Name())
+	if err != nil || len(source) != tokFile.Size() {
+		return nil, false
+	}
+	return source, true
+}
+
+// textEdits turns the line changes between source and sorted into edits of
+// the file, so that drivers only rewrite the lines that moved
+func textEdits(tokFile *token.File, source, sorted []byte) []analysis.TextEdit {
+	oldLines := diff.SplitLines(source)
+	newLines := diff.SplitLines(sorted)
+
+	// offsets[i] is where line i starts, with one extra entry for the end
+	offsets := make([]int, len(oldLines)+1)
+	for i, line := range oldLines {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+
+	var edits []analysis.TextEdit
+	for _, change := range diff.Lines(oldLines, newLines) {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     tokFile.Pos(offsets[change.A1]),
+			End:     tokFile.Pos(offsets[change.A2]),
+			NewText: []byte(strings.Join(newLines[change.B1:change.B2], "")),
+		})
+	}
+	return edits
+}
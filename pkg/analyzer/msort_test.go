@@ -1,14 +1,20 @@
 package analyzer
 
 import (
+	"flag"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/borovikovd/gomsort/pkg/config"
 )
 
 func TestAnalyzerBasicFunctionality(t *testing.T) {
@@ -275,3 +281,140 @@ func (s *Server) helper() error {
 		t.Error("Expected no report for already sorted methods")
 	}
 }
+
+func TestAnalyzerSuggestedFixes(t *testing.T) {
+	// a and c are unsorted and fixed to match their golden files, b is
+	// already sorted
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a", "b", "c")
+}
+
+func resetFlags(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		configPath = ""
+		for _, setting := range settingFlags {
+			setting.value, setting.set = "", false
+		}
+	})
+}
+
+func TestAnalyzerFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags map[string]string
+	}{
+		{"criterion", map[string]string{"exported_first": "false"}},
+		{"exclude", map[string]string{"exclude": "other.go, d.go"}},
+		{"include", map[string]string{"include": "other.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			for name, value := range tt.flags {
+				if err := Analyzer.Flags.Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// d has no expectations, so any diagnostic fails the test
+			analysistest.Run(t, analysistest.TestData(), Analyzer, "d")
+		})
+	}
+}
+
+func TestAnalyzerTypeCheck(t *testing.T) {
+	// Like the CLI, the analyzer guesses calls from receiver names unless
+	// type_check is set, so e has no diagnostics
+	resetFlags(t)
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "e")
+
+	if err := Analyzer.Flags.Set("type_check", "true"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "f")
+}
+
+func TestLoadSettingsFlagsOverrideConfig(t *testing.T) {
+	resetFlags(t)
+
+	configFile := filepath.Join(t.TempDir(), "msort.json")
+	content := `{"sort_criteria": {"exported_first": false, "sort_by_depth": false, "layout": "after_type"}}`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{"config": configFile, "exported_first": "true", "exclude": "gen_*.go"} {
+		if err := Analyzer.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		t.Fatalf("loadSettings() failed: %v", err)
+	}
+
+	criteria := settings.SortCriteria
+	if !criteria.ExportedFirst {
+		t.Error("Expected exported_first flag to override the config file")
+	}
+	if criteria.SortByDepth {
+		t.Error("Expected sort_by_depth from the config file to be kept")
+	}
+	if criteria.Layout != config.LayoutAfterType {
+		t.Errorf("Expected layout from the config file, got %q", criteria.Layout)
+	}
+	if len(settings.Exclude) != 1 || settings.Exclude[0] != "gen_*.go" {
+		t.Errorf("Expected exclude patterns from the flag, got %v", settings.Exclude)
+	}
+}
+
+func TestLoadSettingsFromDriverFlagSet(t *testing.T) {
+	resetFlags(t)
+
+	// Drivers such as singlechecker copy the analyzer's flags into their own
+	// flag set and parse that
+	fs := flag.NewFlagSet("driver", flag.ContinueOnError)
+	Analyzer.Flags.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	if err := fs.Parse([]string{"-exported_first=false", "-layout", "after_type"}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		t.Fatalf("loadSettings() failed: %v", err)
+	}
+	if settings.SortCriteria.ExportedFirst {
+		t.Error("Expected -exported_first=false from the driver's flag set to apply")
+	}
+	if settings.SortCriteria.Layout != config.LayoutAfterType {
+		t.Errorf("Expected layout from the driver's flag set, got %q", settings.SortCriteria.Layout)
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  string
+		value string
+	}{
+		{"missing config", "config", filepath.Join(t.TempDir(), "missing.json")},
+		{"unknown layout", "layout", "sideways"},
+		{"unknown strategy", "strategy", "alphabetical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			if err := Analyzer.Flags.Set(tt.flag, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadSettings(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -2,14 +2,13 @@ package a
 
 type Server struct{}
 
-func (s *Server) Start() error {
-	s.helper()
-	return nil
-}
-
+// helper is called by Start
 func (s *Server) helper() string {
 	return "help"
 }
 
-// want "methods in this file could be better sorted for readability"
-// Methods are in correct order - entry points before helpers
+// Start starts the server
+func (s *Server) Start() error { // want "exported method Start should precede unexported helper"
+	s.helper()
+	return nil
+}
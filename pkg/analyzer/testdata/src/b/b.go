@@ -0,0 +1,12 @@
+package b
+
+type Server struct{}
+
+func (s *Server) Start() error {
+	s.helper()
+	return nil
+}
+
+func (s *Server) helper() string {
+	return "help"
+}
@@ -0,0 +1,10 @@
+package c
+
+type Pool[T any] struct{}
+
+func (p *Pool[T]) release() {}
+
+func (p *Pool[T]) Get() T { // want "exported method Get should precede unexported release"
+	var zero T
+	return zero
+}
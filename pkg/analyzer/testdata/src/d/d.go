@@ -0,0 +1,11 @@
+package d
+
+// Sorted only when exported methods are not required to come first
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Start() {
+	s.helper()
+}
@@ -0,0 +1,16 @@
+package e
+
+// Sorted unless calls are resolved with type information: the receiver is
+// not named after its type, so only the type checker sees Start call b
+
+type Server struct{}
+
+func (srv *Server) Start() {
+	srv.b()
+}
+
+func (srv *Server) a() {}
+
+func (srv *Server) c() {}
+
+func (srv *Server) b() {}
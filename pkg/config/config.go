@@ -2,22 +2,55 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Config struct {
 	SortCriteria SortCriteria `json:"sort_criteria"`
 	Exclude      []string     `json:"exclude"`
 	Include      []string     `json:"include"`
+
+	// root is the directory that patterns with a directory part are
+	// relative to; "" is the working directory
+	root string
 }
 
+// Layouts control where the sorted methods are placed in the file
+const (
+	// LayoutEnd moves all methods to the end of the file
+	LayoutEnd = "end"
+	// LayoutAfterType places each type's methods right after its declaration
+	LayoutAfterType = "after_type"
+)
+
+// Strategies decide the order of each type's methods. These are the built-in
+// ones; the sorter package checks the name, since more can be registered there.
+const (
+	// StrategyDepth orders methods by the criteria: exported first, then by
+	// call depth and in-degree
+	StrategyDepth = "depth"
+	// StrategyStepdown follows each entry point with the methods it calls,
+	// depth-first, as in the stepdown rule
+	StrategyStepdown = "stepdown"
+)
+
 type SortCriteria struct {
-	GroupByReceiver   bool `json:"group_by_receiver"`
-	ExportedFirst     bool `json:"exported_first"`
-	SortByDepth       bool `json:"sort_by_depth"`
-	SortByInDegree    bool `json:"sort_by_in_degree"`
-	PreserveOrigOrder bool `json:"preserve_original_order"`
+	GroupByReceiver   bool   `json:"group_by_receiver"`
+	ExportedFirst     bool   `json:"exported_first"`
+	SortByDepth       bool   `json:"sort_by_depth"`
+	SortByInDegree    bool   `json:"sort_by_in_degree"`
+	PreserveOrigOrder bool   `json:"preserve_original_order"`
+	Strategy          string `json:"strategy"`
+	Layout            string `json:"layout"`
+	KeepConstructors  bool   `json:"keep_constructors"`
+	StrictComments    bool   `json:"strict_comments"`
+
+	// TypeCheck resolves method calls with type information instead of
+	// receiver names, in the CLI and the analyzer alike
+	TypeCheck bool `json:"type_check"`
 }
 
 func DefaultConfig() *Config {
@@ -28,6 +61,8 @@ func DefaultConfig() *Config {
 			SortByDepth:       true,
 			SortByInDegree:    true,
 			PreserveOrigOrder: true,
+			Strategy:          StrategyDepth,
+			Layout:            LayoutEnd,
 		},
 		Exclude: []string{},
 		Include: []string{"*.go"},
@@ -39,21 +74,87 @@ func LoadConfig(configPath string) (*Config, error) {
 		configPath = findConfigFile()
 	}
 
+	// Patterns are relative to the module the configuration file is in; the
+	// defaults and the per-user file apply to the working directory's
+	config := DefaultConfig()
+	config.root = patternRoot(".")
+
 	if configPath == "" {
-		return DefaultConfig(), nil
+		return config, nil
 	}
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return DefaultConfig(), nil
+		return config, nil
+	}
+
+	if configPath != userConfigFile() {
+		config.root = patternRoot(filepath.Dir(configPath))
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	// Start from the defaults so that a partial file only overrides the
+	// settings it mentions
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	return config, nil
+}
+
+// Load loads the configuration file at path, or the discovered one if path is
+// empty. Unlike LoadConfig, it fails if a file the caller asked for is missing
+// rather than falling back to the defaults.
+func Load(path string) (*Config, error) {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
+		}
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return config, nil
+}
+
+// patternRoot returns the module root above dir, the directory holding
+// go.mod, or dir itself outside a module
+func patternRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for current := abs; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return abs
+		}
+	}
+}
+
+func (c *Config) Validate() error {
+	switch c.SortCriteria.Layout {
+	case "", LayoutEnd, LayoutAfterType:
+	default:
+		return fmt.Errorf("unknown layout %q (want %q or %q)", c.SortCriteria.Layout, LayoutEnd, LayoutAfterType)
+	}
+
+	// A malformed pattern would otherwise never match, silently
+	for _, pattern := range append(append([]string(nil), c.Include...), c.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
 }
 
 func findConfigFile() string {
@@ -69,12 +170,10 @@ func findConfigFile() string {
 		}
 	}
 
-	home, err := os.UserHomeDir()
-	if err != nil {
+	homeConfig := userConfigFile()
+	if homeConfig == "" {
 		return ""
 	}
-
-	homeConfig := filepath.Join(home, ".config", "msort", "config.json")
 	if _, err := os.Stat(homeConfig); err == nil {
 		return homeConfig
 	}
@@ -82,6 +181,78 @@ func findConfigFile() string {
 	return ""
 }
 
+// userConfigFile returns the path of the per-user configuration file, or ""
+// if there is no home directory
+func userConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "msort", "config.json")
+}
+
+func (c *Config) ShouldProcess(path string) bool {
+	if c.IsExcluded(path) {
+		return false
+	}
+
+	if len(c.Include) == 0 {
+		return true
+	}
+
+	return c.matchAny(c.Include, path)
+}
+
+func (c *Config) IsExcluded(path string) bool {
+	return c.matchAny(c.Exclude, path)
+}
+
+// Patterns are matched against the base name and, if they have a directory
+// part, against the path relative to the root, so that they mean the same
+// whatever directory gomsort is run from
+func (c *Config) matchAny(patterns []string, path string) bool {
+	base := filepath.Base(path)
+	var relative string
+
+	for _, pattern := range patterns {
+		if ok, err := filepath.Match(pattern, base); err == nil && ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			continue
+		}
+
+		if relative == "" {
+			if relative = c.relative(path); relative == "" {
+				continue
+			}
+		}
+		if ok, err := filepath.Match(filepath.FromSlash(pattern), relative); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// relative returns path relative to the root, or "" if it is outside it
+func (c *Config) relative(path string) string {
+	root, err := filepath.Abs(c.root)
+	if err != nil {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+
+	relative, err := filepath.Rel(root, abs)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return relative
+}
+
 func (c *Config) Save(path string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
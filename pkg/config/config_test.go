@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -26,6 +27,9 @@ func TestDefaultConfig(t *testing.T) {
 	if !config.SortCriteria.PreserveOrigOrder {
 		t.Error("Expected PreserveOrigOrder to be true")
 	}
+	if config.SortCriteria.Strategy != StrategyDepth {
+		t.Errorf("Expected Strategy to be %q, got %q", StrategyDepth, config.SortCriteria.Strategy)
+	}
 
 	if len(config.Exclude) != 0 {
 		t.Errorf("Expected empty Exclude, got %v", config.Exclude)
@@ -41,7 +45,9 @@ func TestLoadConfigWithNoFile(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
+	// Without a file, patterns are relative to the working directory's module
 	expected := DefaultConfig()
+	expected.root = patternRoot(".")
 	if !reflect.DeepEqual(config, expected) {
 		t.Error("Expected default config when no file found")
 	}
@@ -53,7 +59,9 @@ func TestLoadConfigWithNonExistentFile(t *testing.T) {
 		t.Fatalf("Expected no error for non-existent file, got %v", err)
 	}
 
+	// Without a file, patterns are relative to the working directory's module
 	expected := DefaultConfig()
+	expected.root = patternRoot(".")
 	if !reflect.DeepEqual(config, expected) {
 		t.Error("Expected default config when file does not exist")
 	}
@@ -91,11 +99,38 @@ func TestLoadConfigWithValidFile(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
+	testConfig.root = tmpDir
 	if !reflect.DeepEqual(config, testConfig) {
 		t.Errorf("Config mismatch.\nExpected: %+v\nGot: %+v", testConfig, config)
 	}
 }
 
+func TestLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "load.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"exclude": ["vendor"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(config.Exclude, []string{"vendor"}) {
+		t.Errorf("Expected the file's exclude list, got %v", config.Exclude)
+	}
+
+	// Unlike LoadConfig, a file asked for by name must exist
+	config, err = Load(filepath.Join(tmpDir, "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+	if config != nil {
+		t.Error("Expected nil config for a missing file")
+	}
+}
+
 func TestLoadConfigWithInvalidJSON(t *testing.T) {
 	// Create a temporary config file with invalid JSON
 	tmpDir := t.TempDir()
@@ -211,6 +246,7 @@ func TestConfigSave(t *testing.T) {
 		t.Fatalf("Failed to load saved config: %v", err)
 	}
 
+	config.root = tmpDir
 	if !reflect.DeepEqual(config, loadedConfig) {
 		t.Errorf("Saved and loaded configs don't match.\nOriginal: %+v\nLoaded: %+v", config, loadedConfig)
 	}
@@ -243,7 +279,9 @@ func TestLoadConfigWithEmptyPath(t *testing.T) {
 		t.Fatalf("Expected no error with empty path, got %v", err)
 	}
 
+	// Without a file, patterns are relative to the working directory's module
 	expected := DefaultConfig()
+	expected.root = patternRoot(".")
 	if !reflect.DeepEqual(config, expected) {
 		t.Error("Expected default config with empty path")
 	}
@@ -272,3 +310,159 @@ func TestFindConfigFileWithPermissionError(t *testing.T) {
 		t.Errorf("Expected to find %s, got %s", configFile, result)
 	}
 }
+
+func TestLoadConfigWithPartialFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "partial.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"sort_criteria": {"sort_by_depth": false}}`), 0644); err != nil {
+		t.Fatalf("Failed to write partial config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := DefaultConfig()
+	expected.SortCriteria.SortByDepth = false
+	expected.root = tmpDir
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Config mismatch.\nExpected: %+v\nGot: %+v", expected, config)
+	}
+}
+
+func TestShouldProcess(t *testing.T) {
+	config := DefaultConfig()
+	config.Include = []string{"*.go"}
+	config.Exclude = []string{"*_gen.go", "internal/legacy/*"}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"server.go", true},
+		{"pkg/server.go", true},
+		{"pkg/server_gen.go", false},
+		{"internal/legacy/old.go", false},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := config.ShouldProcess(filepath.FromSlash(tt.path)); result != tt.expected {
+				t.Errorf("ShouldProcess(%q) = %v, want %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+
+	config.Include = nil
+	if !config.ShouldProcess("README.md") {
+		t.Error("Expected empty include list to match every file")
+	}
+}
+
+func TestShouldProcessRelativeToConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".msort.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"exclude": ["internal/legacy/*"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{filepath.Join(tmpDir, "internal", "legacy", "old.go"), false},
+		{filepath.Join(tmpDir, "internal", "current", "new.go"), true},
+		{filepath.Join(tmpDir, "legacy", "old.go"), true},
+		{filepath.Join(filepath.Dir(tmpDir), "internal", "legacy", "old.go"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := config.ShouldProcess(tt.path); result != tt.expected {
+				t.Errorf("ShouldProcess(%q) = %v, want %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithInvalidPattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "pattern.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"exclude": ["[a-"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if !errors.Is(err, filepath.ErrBadPattern) {
+		t.Errorf("Expected ErrBadPattern, got %v", err)
+	}
+	if config != nil {
+		t.Error("Expected nil config for an invalid pattern")
+	}
+}
+
+func TestLoadConfigWithUnknownLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "layout.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"sort_criteria": {"layout": "sideways"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err == nil {
+		t.Error("Expected error for unknown layout, got nil")
+	}
+	if config != nil {
+		t.Error("Expected nil config for unknown layout")
+	}
+}
+
+func TestLoadConfigStrategy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		wantErr  bool
+	}{
+		{"default", `{}`, StrategyDepth, false},
+		{"stepdown", `{"sort_criteria": {"strategy": "stepdown"}}`, StrategyStepdown, false},
+		{"registered elsewhere", `{"sort_criteria": {"strategy": "alphabetical"}}`, "alphabetical", false},
+		{"not a string", `{"sort_criteria": {"strategy": 1}}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, tt.name+".json")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if config.SortCriteria.Strategy != tt.expected {
+				t.Errorf("Expected strategy %q, got %q", tt.expected, config.SortCriteria.Strategy)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const contextLines = 3
+
+// Change replaces lines A1..A2 of the old text with lines B1..B2 of the new
+// text (half-open ranges, 0-based)
+type Change struct {
+	A1, A2 int
+	B1, B2 int
+}
+
+// SplitLines splits text into lines that keep their trailing newline, so
+// joining them gives back the original text
+func SplitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes the changes that turn a into b using Myers' algorithm
+func Lines(a, b []string) []Change {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace[d] holds the furthest reaching x for diagonals -d-1..d+1 before
+	// step d, which is all backtracking needs
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, n, m int) []Change {
+	var changes []Change
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		// Skip the diagonal of equal lines
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+
+		change := Change{A1: prevX, A2: x, B1: prevY, B2: y}
+		changes = appendChange(changes, change)
+		x, y = prevX, prevY
+	}
+
+	// Changes were collected back to front
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+	return changes
+}
+
+// appendChange merges single-line edits into the adjacent change
+func appendChange(changes []Change, change Change) []Change {
+	if len(changes) > 0 {
+		last := &changes[len(changes)-1]
+		if last.A1 == change.A2 && last.B1 == change.B2 {
+			last.A1 = change.A1
+			last.B1 = change.B1
+			return changes
+		}
+	}
+	return append(changes, change)
+}
+
+// Unified returns a unified diff between two texts, or nil if they are equal
+func Unified(oldName, newName string, oldText, newText []byte) []byte {
+	a := SplitLines(oldText)
+	b := SplitLines(newText)
+
+	changes := Lines(a, b)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	for len(changes) > 0 {
+		// Group changes whose context would overlap into one hunk
+		end := 1
+		for end < len(changes) && changes[end].A1-changes[end-1].A2 <= 2*contextLines {
+			end++
+		}
+		writeHunk(&buf, a, b, changes[:end])
+		changes = changes[end:]
+	}
+
+	return buf.Bytes()
+}
+
+func writeHunk(buf *bytes.Buffer, a, b []string, changes []Change) {
+	first, last := changes[0], changes[len(changes)-1]
+	a1 := max(first.A1-contextLines, 0)
+	b1 := max(first.B1-contextLines, 0)
+	a2 := min(last.A2+contextLines, len(a))
+	b2 := min(last.B2+contextLines, len(b))
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(a1, a2), hunkRange(b1, b2))
+
+	i := a1
+	for _, change := range changes {
+		for ; i < change.A1; i++ {
+			writeLine(buf, ' ', a[i])
+		}
+		for _, line := range a[change.A1:change.A2] {
+			writeLine(buf, '-', line)
+		}
+		for _, line := range b[change.B1:change.B2] {
+			writeLine(buf, '+', line)
+		}
+		i = change.A2
+	}
+	for ; i < a2; i++ {
+		writeLine(buf, ' ', a[i])
+	}
+}
+
+func writeLine(buf *bytes.Buffer, prefix byte, line string) {
+	buf.WriteByte(prefix)
+	buf.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		buf.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+func hunkRange(start, end int) string {
+	length := end - start
+	if length == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
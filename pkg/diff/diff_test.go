@@ -0,0 +1,120 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func apply(a, b []string, changes []Change) []string {
+	var result []string
+	i := 0
+	for _, change := range changes {
+		result = append(result, a[i:change.A1]...)
+		result = append(result, b[change.B1:change.B2]...)
+		i = change.A2
+	}
+	return append(result, a[i:]...)
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected []Change
+	}{
+		{"equal", "a\nb\n", "a\nb\n", nil},
+		{"empty", "", "", nil},
+		{"insert", "a\nc\n", "a\nb\nc\n", []Change{{A1: 1, A2: 1, B1: 1, B2: 2}}},
+		{"delete", "a\nb\nc\n", "a\nc\n", []Change{{A1: 1, A2: 2, B1: 1, B2: 1}}},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", []Change{{A1: 1, A2: 2, B1: 1, B2: 2}}},
+		{"swap", "a\nb\n", "b\na\n", []Change{{A1: 0, A2: 1, B1: 0, B2: 0}, {A1: 2, A2: 2, B1: 1, B2: 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Lines(SplitLines([]byte(tt.a)), SplitLines([]byte(tt.b)))
+			if len(changes) != len(tt.expected) {
+				t.Fatalf("Lines() = %+v, want %+v", changes, tt.expected)
+			}
+			for i := range changes {
+				if changes[i] != tt.expected[i] {
+					t.Errorf("Lines() = %+v, want %+v", changes, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a\n", "b\n", "c\n", "d\n"}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(20))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		if got := apply(a, b, Lines(a, b)); strings.Join(got, "") != strings.Join(b, "") {
+			t.Fatalf("Applying changes to %q gave %q, want %q", a, got, b)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	before := "package test\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n\nfunc d() {}\n\nfunc e() {}\n"
+	after := "package test\n\nfunc a() {}\n\nfunc b() {}\n\nfunc d() {}\n\nfunc c() {}\n\nfunc e() {}\n"
+
+	expected := "--- test.go.orig\n" +
+		"+++ test.go\n" +
+		"@@ -4,8 +4,8 @@\n" +
+		" \n" +
+		" func b() {}\n" +
+		" \n" +
+		"-func c() {}\n" +
+		"-\n" +
+		" func d() {}\n" +
+		" \n" +
+		"+func c() {}\n" +
+		"+\n" +
+		" func e() {}\n"
+
+	if result := string(Unified("test.go.orig", "test.go", []byte(before), []byte(after))); result != expected {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", result, expected)
+	}
+
+	if result := Unified("a", "b", []byte(before), []byte(before)); result != nil {
+		t.Errorf("Expected nil diff for equal input, got:\n%s", result)
+	}
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 30; i++ {
+		line := string(rune('a'+i%26)) + "\n"
+		oldLines = append(oldLines, line)
+		newLines = append(newLines, line)
+	}
+	newLines[2] = "X\n"
+	newLines[25] = "Y\n"
+
+	result := string(Unified("a", "b", []byte(strings.Join(oldLines, "")), []byte(strings.Join(newLines, ""))))
+	if strings.Count(result, "@@ -") != 2 {
+		t.Errorf("Expected two hunks, got:\n%s", result)
+	}
+	if !strings.Contains(result, "@@ -1,6 +1,6 @@") || !strings.Contains(result, "@@ -23,7 +23,7 @@") {
+		t.Errorf("Unexpected hunk headers:\n%s", result)
+	}
+}
+
+func TestUnifiedNoTrailingNewline(t *testing.T) {
+	result := string(Unified("a", "b", []byte("x\ny"), []byte("x\ny\n")))
+	if !strings.Contains(result, "-y\n\\ No newline at end of file\n+y\n") {
+		t.Errorf("Expected missing newline marker, got:\n%s", result)
+	}
+}
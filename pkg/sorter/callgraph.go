@@ -2,6 +2,7 @@ package sorter
 
 import (
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dave/dst"
@@ -21,32 +22,36 @@ func NewCallGraph() *CallGraph {
 	}
 }
 
-func buildCallGraph(file *dst.File) *CallGraph {
+// buildCallGraph builds the call graph of the given files, which share one
+// graph when they belong to the same package
+func buildCallGraph(files ...*dst.File) *CallGraph {
 	cg := NewCallGraph()
 
 	// First pass: collect all methods
 	position := 0
-	for _, decl := range file.Decls {
-		if funcDecl, ok := decl.(*dst.FuncDecl); ok {
-			if method := extractMethodInfo(funcDecl, position); method != nil {
-				cg.AddMethod(method)
-				position++
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*dst.FuncDecl); ok {
+				if method := extractMethodInfo(funcDecl, position); method != nil {
+					cg.AddMethod(method)
+					position++
+				}
 			}
 		}
 	}
 
-	// Second pass: analyze method calls
-	for _, decl := range file.Decls {
-		if funcDecl, ok := decl.(*dst.FuncDecl); ok {
-			if method := extractMethodInfo(funcDecl, 0); method != nil && funcDecl.Body != nil {
-				visitor := &callVisitor{
-					callGraph:       cg,
-					currentReceiver: method.ReceiverName,
-					currentMethod:   method.Name,
-				}
-				dst.Walk(visitor, funcDecl.Body)
-			}
+	// Second pass: analyze method calls. A blank method never runs, so its
+	// calls do not count.
+	for _, method := range cg.GetMethods() {
+		if method.isBlank() || method.FuncDecl.Body == nil {
+			continue
+		}
+		visitor := &callVisitor{
+			callGraph:       cg,
+			currentReceiver: method.ReceiverName,
+			currentKey:      method.key(),
 		}
+		dst.Walk(visitor, method.FuncDecl.Body)
 	}
 
 	cg.CalculateMetrics()
@@ -56,7 +61,7 @@ func buildCallGraph(file *dst.File) *CallGraph {
 type callVisitor struct {
 	callGraph       *CallGraph
 	currentReceiver string
-	currentMethod   string
+	currentKey      string
 }
 
 func methodKey(receiver, method string) string {
@@ -64,20 +69,47 @@ func methodKey(receiver, method string) string {
 }
 
 func (cg *CallGraph) AddMethod(method *MethodInfo) {
+	// A method declared more than once, which only compiles for blank
+	// methods, is told apart by its position
 	key := methodKey(method.ReceiverName, method.Name)
+	if _, taken := cg.methods[key]; taken || method.isBlank() {
+		key += "#" + strconv.Itoa(method.Position)
+	}
+	method.id = key
 	cg.methods[key] = method
 	cg.positions[key] = method.Position
 }
 
 func (cg *CallGraph) AddCall(fromReceiver, fromMethod, toReceiver, toMethod string) {
-	fromKey := methodKey(fromReceiver, fromMethod)
-	toKey := methodKey(toReceiver, toMethod)
+	cg.addCall(methodKey(fromReceiver, fromMethod), methodKey(toReceiver, toMethod))
+}
 
+func (cg *CallGraph) addCall(fromKey, toKey string) {
 	if _, exists := cg.methods[toKey]; exists {
 		cg.calls[fromKey] = append(cg.calls[fromKey], toKey)
 	}
 }
 
+// Calls returns the methods that method calls, each once, in the order of
+// their first call
+func (cg *CallGraph) Calls(method *MethodInfo) []*MethodInfo {
+	seen := make(map[string]bool)
+	var callees []*MethodInfo
+	from := method.key()
+	if cg.methods[from] != method {
+		// A method of another graph, such as a file's in the package
+		// graph, is looked up by name
+		from = methodKey(method.ReceiverName, method.Name)
+	}
+	for _, key := range cg.calls[from] {
+		if !seen[key] {
+			seen[key] = true
+			callees = append(callees, cg.methods[key])
+		}
+	}
+	return callees
+}
+
 func (cg *CallGraph) CalculateMetrics() {
 	// Calculate in-degree for each method
 	inDegree := make(map[string]int)
@@ -98,10 +130,9 @@ func (cg *CallGraph) CalculateMetrics() {
 		}
 	}
 
-	// Calculate max depth for each method using DFS
+	depths := cg.calculateDepths(keys)
 	for _, key := range keys {
-		visited := make(map[string]bool)
-		cg.methods[key].MaxDepth = cg.calculateMaxDepth(key, visited)
+		cg.methods[key].MaxDepth = depths[key]
 		cg.methods[key].InDegree = inDegree[key]
 	}
 }
@@ -129,25 +160,93 @@ func (cg *CallGraph) GetMethods() []*MethodInfo {
 	return methods
 }
 
-func (cg *CallGraph) calculateMaxDepth(methodKey string, visited map[string]bool) int {
-	if visited[methodKey] {
-		return 0
+// calculateDepths returns the length of the longest call chain from each
+// method, in one pass over the graph. Methods that call each other, directly
+// or through others, share a depth: a chain into the cycle walks all of it
+// once and may then leave it through any of its methods.
+func (cg *CallGraph) calculateDepths(keys []string) map[string]int {
+	depths := make(map[string]int, len(keys))
+
+	// Tarjan's algorithm finds the cycles, as strongly connected components,
+	// and completes each after every component it calls into
+	index := make(map[string]int, len(keys))
+	lowlink := make(map[string]int, len(keys))
+	onStack := make(map[string]bool)
+	var stack []string
+
+	var visit func(key string)
+	visit = func(key string) {
+		index[key] = len(index)
+		lowlink[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, callee := range cg.calls[key] {
+			if _, seen := index[callee]; !seen {
+				visit(callee)
+				lowlink[key] = min(lowlink[key], lowlink[callee])
+			} else if onStack[callee] {
+				lowlink[key] = min(lowlink[key], index[callee])
+			}
+		}
+
+		if lowlink[key] != index[key] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == key {
+				break
+			}
+		}
+		cg.componentDepth(component, depths)
 	}
 
-	visited[methodKey] = true
-	maxDepth := 0
+	for _, key := range keys {
+		if _, seen := index[key]; !seen {
+			visit(key)
+		}
+	}
+	return depths
+}
+
+// componentDepth sets the depth of the methods in component, whose callees
+// outside it already have theirs
+func (cg *CallGraph) componentDepth(component []string, depths map[string]int) {
+	members := make(map[string]bool, len(component))
+	for _, key := range component {
+		members[key] = true
+	}
 
-	if calls, exists := cg.calls[methodKey]; exists {
-		for _, calledMethod := range calls {
-			depth := cg.calculateMaxDepth(calledMethod, visited)
-			if depth+1 > maxDepth {
-				maxDepth = depth + 1
+	// Going around a cycle, including a method calling itself, counts one
+	// call per method in it
+	cyclic := len(component) > 1
+	exit := -1
+	for _, key := range component {
+		for _, callee := range cg.calls[key] {
+			if members[callee] {
+				cyclic = true
+				continue
 			}
+			exit = max(exit, depths[callee])
 		}
 	}
 
-	delete(visited, methodKey)
-	return maxDepth
+	depth := 0
+	if cyclic {
+		depth = len(component)
+	}
+	if exit >= 0 {
+		depth = max(depth, len(component)+exit)
+	}
+	for _, key := range component {
+		depths[key] = depth
+	}
 }
 
 func (v *callVisitor) Visit(node dst.Node) dst.Visitor {
@@ -158,7 +257,7 @@ func (v *callVisitor) Visit(node dst.Node) dst.Visitor {
 				if ident.Name == "self" || ident.Name == v.currentReceiver ||
 					(len(ident.Name) == 1 && len(v.currentReceiver) > 0 &&
 						strings.EqualFold(ident.Name[0:1], v.currentReceiver[0:1])) {
-					v.callGraph.AddCall(v.currentReceiver, v.currentMethod, v.currentReceiver, sel.Sel.Name)
+					v.callGraph.addCall(v.currentKey, methodKey(v.currentReceiver, sel.Sel.Name))
 				}
 			}
 		}
@@ -1,6 +1,8 @@
 package sorter
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/dave/dst/decorator"
@@ -139,6 +141,65 @@ func (s *Server) listen() error {
 	}
 }
 
+func TestCallGraphWithGenericReceivers(t *testing.T) {
+	source := `
+package test
+
+type List[T any] struct{}
+type Map[K comparable, V any] struct{}
+
+func (l *List[T]) Push(v T) {
+	l.grow()
+}
+
+func (l *List[T]) grow() {}
+
+func (m Map[K, V]) Get(k K) V {
+	return m.lookup(k)
+}
+
+func (m Map[K, V]) lookup(k K) V {
+	var v V
+	return v
+}
+`
+
+	file, err := decorator.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cg := buildCallGraph(file)
+
+	expected := map[string]struct {
+		inDegree int
+		maxDepth int
+	}{
+		"List.Push":  {0, 1},
+		"List.grow":  {1, 0},
+		"Map.Get":    {0, 1},
+		"Map.lookup": {1, 0},
+	}
+
+	methods := cg.GetMethods()
+	if len(methods) != len(expected) {
+		t.Fatalf("Expected %d methods, got %d", len(expected), len(methods))
+	}
+
+	for _, method := range methods {
+		key := methodKey(method.ReceiverName, method.Name)
+		want, ok := expected[key]
+		if !ok {
+			t.Errorf("Unexpected method %s", key)
+			continue
+		}
+		if method.InDegree != want.inDegree || method.MaxDepth != want.maxDepth {
+			t.Errorf("%s: InDegree=%d MaxDepth=%d, want InDegree=%d MaxDepth=%d",
+				key, method.InDegree, method.MaxDepth, want.inDegree, want.maxDepth)
+		}
+	}
+}
+
 func TestCallGraphCycleDetection(t *testing.T) {
 	source := `
 package test
@@ -175,6 +236,87 @@ func (s *Server) methodB() error {
 	}
 }
 
+func TestCallGraphDepths(t *testing.T) {
+	cg := NewCallGraph()
+	for i, name := range []string{"entry", "a", "b", "self", "leaf"} {
+		cg.AddMethod(&MethodInfo{ReceiverName: "Server", Name: name, Position: i})
+	}
+	// entry -> a <-> b -> leaf, and self calls itself
+	cg.AddCall("Server", "entry", "Server", "a")
+	cg.AddCall("Server", "a", "Server", "b")
+	cg.AddCall("Server", "b", "Server", "a")
+	cg.AddCall("Server", "b", "Server", "leaf")
+	cg.AddCall("Server", "self", "Server", "self")
+	cg.CalculateMetrics()
+
+	expected := map[string]int{"entry": 3, "a": 2, "b": 2, "self": 1, "leaf": 0}
+	for name, depth := range expected {
+		if got := cg.methods[methodKey("Server", name)].MaxDepth; got != depth {
+			t.Errorf("%s: MaxDepth=%d, want %d", name, got, depth)
+		}
+	}
+}
+
+func TestCallGraphDepthsOfDenseGraph(t *testing.T) {
+	// Every method calls all the ones after it, which has exponentially many
+	// call chains; depths must still be computed in one pass
+	const count = 200
+	cg := NewCallGraph()
+	for i := 0; i < count; i++ {
+		cg.AddMethod(&MethodInfo{ReceiverName: "Server", Name: fmt.Sprintf("m%03d", i), Position: i})
+	}
+	for i := 0; i < count; i++ {
+		for j := i + 1; j < count; j++ {
+			cg.AddCall("Server", fmt.Sprintf("m%03d", i), "Server", fmt.Sprintf("m%03d", j))
+		}
+	}
+	cg.CalculateMetrics()
+
+	if depth := cg.methods[methodKey("Server", "m000")].MaxDepth; depth != count-1 {
+		t.Errorf("Expected m000 to have depth %d, got %d", count-1, depth)
+	}
+}
+
+func TestCallGraphCalls(t *testing.T) {
+	source := `
+package test
+
+type Server struct{}
+
+func (s *Server) Start() {
+	s.validate()
+	s.connect()
+	s.validate()
+	s.Start()
+}
+
+func (s *Server) connect() {}
+
+func (s *Server) validate() {}
+`
+
+	file, err := decorator.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cg := buildCallGraph(file)
+	methods := cg.GetMethods()
+
+	var names []string
+	for _, callee := range cg.Calls(methods[0]) {
+		names = append(names, callee.Name)
+	}
+	expected := []string{"validate", "connect", "Start"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, names)
+	}
+
+	if calls := cg.Calls(methods[1]); len(calls) != 0 {
+		t.Errorf("Expected connect to call nothing, got %v", calls)
+	}
+}
+
 func TestMethodKey(t *testing.T) {
 	tests := []struct {
 		receiver string
@@ -0,0 +1,127 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+var ErrAmbiguousComment = errors.New("ambiguous comment ownership")
+
+// attachDetachedComments makes free-floating comments travel with the
+// declaration they precede. dst hands a comment that sits right below a
+// declaration, separated from the next one by a blank line, to the End of the
+// declaration above, so it would otherwise move with the wrong neighbour.
+// Comments trailing the last declaration belong to the file and are returned
+// so they can be put back at the end once the declarations are reordered.
+func attachDetachedComments(decls []dst.Decl, moved map[dst.Decl]bool) []string {
+	for i := 1; i < len(decls); i++ {
+		prev, next := decls[i-1], decls[i]
+		if !moved[prev] && !moved[next] {
+			continue
+		}
+
+		inline, detached := splitEnd(prev.Decorations().End.All())
+		detached = trimNewlines(detached)
+		if len(detached) == 0 {
+			continue
+		}
+
+		prev.Decorations().End.Replace(inline...)
+		next.Decorations().Start.Prepend("\n")
+		next.Decorations().Start.Prepend(detached...)
+	}
+
+	if len(decls) == 0 || !moved[decls[len(decls)-1]] {
+		return nil
+	}
+
+	last := decls[len(decls)-1]
+	inline, trailing := splitEnd(last.Decorations().End.All())
+	last.Decorations().End.Replace(inline...)
+	return append([]string{}, trailing...)
+}
+
+// splitEnd separates comments on the declaration's closing line from the
+// lines below it. A line comment ends the line, so dst records no line break
+// between it and the comments that follow.
+func splitEnd(end []string) ([]string, []string) {
+	i := 0
+	for i < len(end) && end[i] != "\n" {
+		endsLine := strings.HasPrefix(end[i], "//")
+		i++
+		if endsLine {
+			break
+		}
+	}
+	return end[:i], end[i:]
+}
+
+func trimNewlines(decs []string) []string {
+	for len(decs) > 0 && decs[0] == "\n" {
+		decs = decs[1:]
+	}
+	return decs
+}
+
+// restoreTrailingComments puts the file's trailing comments back after the
+// new last declaration
+func restoreTrailingComments(decls []dst.Decl, trailing []string) {
+	if len(decls) == 0 || len(trimNewlines(trailing)) == 0 {
+		return
+	}
+
+	decls[len(decls)-1].Decorations().End.Append(trailing...)
+}
+
+// checkCommentOwnership fails when a declaration that gets a new neighbour
+// carries a comment separated from it by a blank line: such a comment may be
+// a section header for several declarations rather than part of this one.
+func checkCommentOwnership(original, reordered []dst.Decl, moved map[dst.Decl]bool) error {
+	prevOriginal := make(map[dst.Decl]dst.Decl, len(original))
+	for i := 1; i < len(original); i++ {
+		prevOriginal[original[i]] = original[i-1]
+	}
+
+	for i, decl := range reordered {
+		if !moved[decl] || !hasDetachedComment(decl.Decorations().Start.All()) {
+			continue
+		}
+
+		var prev dst.Decl
+		if i > 0 {
+			prev = reordered[i-1]
+		}
+		if prev != prevOriginal[decl] {
+			return fmt.Errorf("%w: comment separated from %s by a blank line", ErrAmbiguousComment, declName(decl))
+		}
+	}
+
+	return nil
+}
+
+func hasDetachedComment(start []string) bool {
+	seenComment := false
+	for _, entry := range start {
+		if entry != "\n" {
+			seenComment = true
+		} else if seenComment {
+			return true
+		}
+	}
+	return false
+}
+
+func declName(decl dst.Decl) string {
+	funcDecl, ok := decl.(*dst.FuncDecl)
+	if !ok {
+		return "declaration"
+	}
+
+	if method := extractMethodInfo(funcDecl, 0); method != nil && method.ReceiverName != "" {
+		return methodKey(method.ReceiverName, method.Name)
+	}
+	return funcDecl.Name.Name
+}
@@ -0,0 +1,219 @@
+package sorter
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/dave/dst"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+// Misplacement is a method that the sort moves, with the reason it belongs
+// next to a different method
+type Misplacement struct {
+	ReceiverName string
+	Name         string
+	Criterion    Criterion
+	Message      string
+
+	// Pos is the position of the method name for sorters built with
+	// NewFromFile, token.NoPos otherwise
+	Pos token.Pos
+}
+
+// Placement is where a method ends up after sorting, with the metrics it
+// was sorted by
+type Placement struct {
+	ReceiverName string
+	Name         string
+	IsExported   bool
+	MaxDepth     int
+	InDegree     int
+
+	// From and To are the method's index among the file's methods before
+	// and after sorting
+	From int
+	To   int
+
+	// After is the method of the same receiver placed right before this
+	// one, "" for the first, and Criterion the rule that puts it there
+	After     string
+	Criterion Criterion
+
+	// Pos is the position of the method name for sorters built with
+	// NewFromFile, token.NoPos otherwise
+	Pos token.Pos
+}
+
+// Placements returns every method in its order after the last call to Sort,
+// including when nothing had to move
+func (s *Sorter) Placements() []Placement {
+	return s.placements
+}
+
+// Misplaced returns the methods moved by the last call to Sort, in their new
+// order. Methods that only shift because others move around them are left
+// out.
+func (s *Sorter) Misplaced() []Misplacement {
+	return s.misplaced
+}
+
+func methodPlacements(order []*MethodInfo, strategy Strategy, criteria config.SortCriteria, astNodes map[dst.Node]ast.Node) []Placement {
+	placements := make([]Placement, 0, len(order))
+	last := make(map[string]*MethodInfo)
+	for i, method := range order {
+		placement := Placement{
+			ReceiverName: method.ReceiverName,
+			Name:         method.Name,
+			IsExported:   method.IsExported,
+			MaxDepth:     method.MaxDepth,
+			InDegree:     method.InDegree,
+			From:         method.Position,
+			To:           i,
+			Pos:          methodPos(method, astNodes),
+		}
+		if prev := last[method.ReceiverName]; prev != nil {
+			placement.After = prev.Name
+			placement.Criterion = orderingCriterion(strategy, prev, method, criteria)
+		}
+		last[method.ReceiverName] = method
+		placements = append(placements, placement)
+	}
+	return placements
+}
+
+func methodPos(method *MethodInfo, astNodes map[dst.Node]ast.Node) token.Pos {
+	if astDecl, ok := astNodes[method.FuncDecl].(*ast.FuncDecl); ok {
+		return astDecl.Name.Pos()
+	}
+	return token.NoPos
+}
+
+func misplacedMethods(order []*MethodInfo, strategy Strategy, criteria config.SortCriteria, astNodes map[dst.Node]ast.Node) []Misplacement {
+	kept := keptInOrder(order)
+
+	var misplaced []Misplacement
+	for i, method := range order {
+		if kept[method] {
+			continue
+		}
+
+		// Explain the move against a neighbour of the same type where there
+		// is one, since that is where the method has to be looked for
+		var other *MethodInfo
+		precede := true
+		switch {
+		case i+1 < len(order) && order[i+1].ReceiverName == method.ReceiverName:
+			other = order[i+1]
+		case i > 0 && order[i-1].ReceiverName == method.ReceiverName:
+			other, precede = order[i-1], false
+		case i+1 < len(order):
+			other = order[i+1]
+		case i > 0:
+			other, precede = order[i-1], false
+		default:
+			continue
+		}
+
+		var criterion Criterion
+		if precede {
+			criterion = orderingCriterion(strategy, method, other, criteria)
+		} else {
+			criterion = orderingCriterion(strategy, other, method, criteria)
+		}
+
+		misplaced = append(misplaced, Misplacement{
+			ReceiverName: method.ReceiverName,
+			Name:         method.Name,
+			Criterion:    criterion,
+			Message:      misplacementMessage(method, other, precede, criterion, criteria.Layout),
+			Pos:          methodPos(method, astNodes),
+		})
+	}
+
+	return misplaced
+}
+
+// keptInOrder returns the longest run of methods whose original order is
+// unchanged in the new order; the others are the ones that actually moved
+func keptInOrder(order []*MethodInfo) map[*MethodInfo]bool {
+	length := make([]int, len(order))
+	prev := make([]int, len(order))
+	best := -1
+	for i := range order {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if order[j].Position < order[i].Position && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		// Ties favour runs ending later, which blames the methods moving up
+		// rather than the ones they overtake
+		if best < 0 || length[i] >= length[best] {
+			best = i
+		}
+	}
+
+	kept := make(map[*MethodInfo]bool, len(order))
+	for i := best; i >= 0; i = prev[i] {
+		kept[order[i]] = true
+	}
+	return kept
+}
+
+func misplacementMessage(method, other *MethodInfo, precede bool, criterion Criterion, layout string) string {
+	relation := "should precede"
+	if !precede {
+		relation = "should come after"
+	}
+
+	switch criterion {
+	case CriterionReceiver:
+		if layout == config.LayoutAfterType {
+			return fmt.Sprintf("method %s belongs with type %s",
+				methodKey(method.ReceiverName, method.Name), method.ReceiverName)
+		}
+		return fmt.Sprintf("methods of %s %s methods of %s", method.ReceiverName, relation, other.ReceiverName)
+	case CriterionExported:
+		return fmt.Sprintf("%s method %s %s %s %s",
+			visibility(method), method.Name, relation, visibility(other), other.Name)
+	case CriterionDepth:
+		return fmt.Sprintf("method %s (depth %d) %s %s (depth %d)",
+			method.Name, method.MaxDepth, relation, other.Name, other.MaxDepth)
+	case CriterionInDegree:
+		return fmt.Sprintf("method %s (depth %d, in-degree %d) %s %s (depth %d, in-degree %d)",
+			method.Name, method.MaxDepth, method.InDegree, relation, other.Name, other.MaxDepth, other.InDegree)
+	case CriterionPosition:
+		return fmt.Sprintf("method %s %s %s as in the original order", method.Name, relation, other.Name)
+	case CriterionCallOrder:
+		return fmt.Sprintf("method %s %s %s in call order", method.Name, relation, other.Name)
+	case CriterionStrategy:
+		return fmt.Sprintf("method %s %s %s in the strategy's order", method.Name, relation, other.Name)
+	}
+	return fmt.Sprintf("method %s %s %s", method.Name, relation, other.Name)
+}
+
+func visibility(method *MethodInfo) string {
+	if method.IsExported {
+		return "exported"
+	}
+	return "unexported"
+}
+
+// methodOrder returns the methods in the order they appear in decls
+func methodOrder(methods []*MethodInfo, decls []dst.Decl) []*MethodInfo {
+	byDecl := make(map[dst.Decl]*MethodInfo, len(methods))
+	for _, method := range methods {
+		byDecl[method.FuncDecl] = method
+	}
+
+	order := make([]*MethodInfo, 0, len(methods))
+	for _, decl := range decls {
+		if method, ok := byDecl[decl]; ok {
+			order = append(order, method)
+		}
+	}
+	return order
+}
@@ -0,0 +1,215 @@
+package sorter
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+func TestMisplaced(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		layout   string
+		expected []Misplacement
+	}{
+		{
+			name: "exported first",
+			source: `package test
+
+type Server struct{}
+
+func (s *Server) connect() {}
+
+func (s *Server) Stop() {}
+`,
+			expected: []Misplacement{
+				{"Server", "Stop", CriterionExported, "exported method Stop should precede unexported connect", token.NoPos},
+			},
+		},
+		{
+			name: "depth",
+			source: `package test
+
+type Server struct{}
+
+func (s *Server) run() {
+	s.step()
+}
+
+func (s *Server) step() {
+	s.close()
+}
+
+func (s *Server) close() {}
+`,
+			expected: []Misplacement{
+				{"Server", "close", CriterionDepth, "method close (depth 0) should precede step (depth 1)", token.NoPos},
+				{"Server", "step", CriterionDepth, "method step (depth 1) should precede run (depth 2)", token.NoPos},
+			},
+		},
+		{
+			name: "in-degree",
+			source: `package test
+
+type Server struct{}
+
+func (s *Server) close() {}
+
+func (s *Server) open() {
+	s.cleanup()
+}
+
+func (s *Server) reset() {
+	s.cleanup()
+}
+
+func (s *Server) cleanup() {}
+`,
+			expected: []Misplacement{
+				{"Server", "cleanup", CriterionInDegree, "method cleanup (depth 0, in-degree 2) should precede close (depth 0, in-degree 0)", token.NoPos},
+			},
+		},
+		{
+			name: "receiver",
+			source: `package test
+
+type Client struct{}
+type Server struct{}
+
+func (s *Server) Start() {}
+
+func (c *Client) Connect() {}
+`,
+			expected: []Misplacement{
+				{"Client", "Connect", CriterionReceiver, "methods of Client should precede methods of Server", token.NoPos},
+			},
+		},
+		{
+			name: "receiver after type",
+			source: `package test
+
+type Server struct{}
+
+type Client struct{}
+
+func (c *Client) Connect() {}
+
+func (s *Server) Start() {}
+`,
+			layout: config.LayoutAfterType,
+			expected: []Misplacement{
+				{"Server", "Start", CriterionReceiver, "method Server.Start belongs with type Server", token.NoPos},
+			},
+		},
+		{
+			name: "sorted",
+			source: `package test
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+func (s *Server) stop() {}
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter, err := NewFromSource(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			criteria := config.DefaultConfig().SortCriteria
+			if tt.layout != "" {
+				criteria.Layout = tt.layout
+			}
+			sorter.SetCriteria(criteria)
+
+			if _, _, err := sorter.Sort(); err != nil {
+				t.Fatal(err)
+			}
+
+			misplaced := sorter.Misplaced()
+			if len(misplaced) != len(tt.expected) {
+				t.Fatalf("Misplaced() = %+v, want %+v", misplaced, tt.expected)
+			}
+			for i := range tt.expected {
+				if misplaced[i] != tt.expected[i] {
+					t.Errorf("Misplaced()[%d] = %+v, want %+v", i, misplaced[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) step() {}
+
+func (s *Server) Start() { s.run() }
+
+func (s *Server) run() { s.step() }
+
+func (s *Server) close() {}
+
+type Client struct{}
+
+func (c *Client) Do() {}
+`
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sorter.Sort(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Placement{
+		{"Client", "Do", true, 0, 0, 4, 0, "", CriterionNone, token.NoPos},
+		{"Server", "Start", true, 2, 0, 1, 1, "", CriterionNone, token.NoPos},
+		{"Server", "step", false, 0, 1, 0, 2, "Start", CriterionExported, token.NoPos},
+		{"Server", "close", false, 0, 0, 3, 3, "step", CriterionInDegree, token.NoPos},
+		{"Server", "run", false, 1, 1, 2, 4, "close", CriterionDepth, token.NoPos},
+	}
+
+	placements := sorter.Placements()
+	if len(placements) != len(expected) {
+		t.Fatalf("Placements() = %+v, want %+v", placements, expected)
+	}
+	for i := range expected {
+		if placements[i] != expected[i] {
+			t.Errorf("Placements()[%d] = %+v, want %+v", i, placements[i], expected[i])
+		}
+	}
+}
+
+func TestPlacementsOfSortedFile(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) Start() { s.stop() }
+
+func (s *Server) stop() {}
+`
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err := sorter.Sort(); err != nil || changed {
+		t.Fatalf("Expected a sorted file, got changed=%v, err=%v", changed, err)
+	}
+
+	placements := sorter.Placements()
+	if len(placements) != 2 || placements[1].After != "Start" || placements[1].Criterion != CriterionExported {
+		t.Errorf("Unexpected placements %+v", placements)
+	}
+}
@@ -0,0 +1,198 @@
+package sorter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// graphType holds the methods of one receiver type, in source order
+type graphType struct {
+	name    string
+	methods []*MethodInfo
+}
+
+type graphJSON struct {
+	Types []typeJSON `json:"types"`
+}
+
+type typeJSON struct {
+	Name    string       `json:"name"`
+	Methods []methodJSON `json:"methods"`
+}
+
+type methodJSON struct {
+	Name     string   `json:"name"`
+	Exported bool     `json:"exported"`
+	InDegree int      `json:"in_degree"`
+	MaxDepth int      `json:"max_depth"`
+	Calls    []string `json:"calls"`
+}
+
+// CallGraph returns the graph the sorter orders methods by: the package's
+// if the sorter was linked, otherwise the file's
+func (s *Sorter) CallGraph() *CallGraph {
+	if s.packageGraph != nil {
+		return s.packageGraph
+	}
+	return s.buildCallGraph()
+}
+
+// ForType returns the part of the graph made of the methods of receiver and
+// the calls between them. Metrics are those of the whole graph.
+func (cg *CallGraph) ForType(receiver string) *CallGraph {
+	sub := NewCallGraph()
+	for key, method := range cg.methods {
+		if method.ReceiverName == receiver {
+			sub.methods[key] = method
+			sub.positions[key] = cg.positions[key]
+		}
+	}
+
+	for from, calls := range cg.calls {
+		if _, ok := sub.methods[from]; !ok {
+			continue
+		}
+		for _, to := range calls {
+			if _, ok := sub.methods[to]; ok {
+				sub.calls[from] = append(sub.calls[from], to)
+			}
+		}
+	}
+	return sub
+}
+
+// Len returns the number of methods in the graph
+func (cg *CallGraph) Len() int {
+	return len(cg.methods)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with a cluster per
+// receiver type and each method labelled with its in-degree and depth
+func (cg *CallGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph calls {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	for i, typ := range cg.types() {
+		fmt.Fprintf(bw, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(bw, "\t\tlabel=%s;\n", strconv.Quote(typ.name))
+		for _, method := range typ.methods {
+			key := method.key()
+			fmt.Fprintf(bw, "\t\t%s [label=%s];\n", strconv.Quote(key), strconv.Quote(methodLabel(method, "\n")))
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+
+	for _, from := range cg.keys() {
+		for _, to := range cg.callees(from) {
+			fmt.Fprintf(bw, "\t%s -> %s;\n", strconv.Quote(from), strconv.Quote(to))
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, with a subgraph per
+// receiver type
+func (cg *CallGraph) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart TD")
+
+	// Mermaid ids cannot hold every character of a receiver name
+	ids := make(map[string]string, len(cg.methods))
+	for i, typ := range cg.types() {
+		fmt.Fprintf(bw, "\tsubgraph t%d[\"%s\"]\n", i, typ.name)
+		for _, method := range typ.methods {
+			key := method.key()
+			ids[key] = fmt.Sprintf("m%d", len(ids))
+			fmt.Fprintf(bw, "\t\t%s[\"%s\"]\n", ids[key], methodLabel(method, "<br/>"))
+		}
+		fmt.Fprintln(bw, "\tend")
+	}
+
+	for _, from := range cg.keys() {
+		for _, to := range cg.callees(from) {
+			fmt.Fprintf(bw, "\t%s --> %s\n", ids[from], ids[to])
+		}
+	}
+
+	return bw.Flush()
+}
+
+// MarshalJSON encodes the graph as its receiver types, each with its methods
+// in source order and the methods they call as "Receiver.Name"
+func (cg *CallGraph) MarshalJSON() ([]byte, error) {
+	graph := graphJSON{Types: []typeJSON{}}
+	for _, typ := range cg.types() {
+		entry := typeJSON{Name: typ.name, Methods: []methodJSON{}}
+		for _, method := range typ.methods {
+			calls := cg.callees(method.key())
+			if calls == nil {
+				calls = []string{}
+			}
+			entry.Methods = append(entry.Methods, methodJSON{
+				Name:     method.Name,
+				Exported: method.IsExported,
+				InDegree: method.InDegree,
+				MaxDepth: method.MaxDepth,
+				Calls:    calls,
+			})
+		}
+		graph.Types = append(graph.Types, entry)
+	}
+	return json.Marshal(graph)
+}
+
+// types groups the methods by receiver type, with the types in alphabetical
+// order
+func (cg *CallGraph) types() []graphType {
+	var types []graphType
+	index := make(map[string]int)
+	for _, method := range cg.GetMethods() {
+		i, ok := index[method.ReceiverName]
+		if !ok {
+			i = len(types)
+			index[method.ReceiverName] = i
+			types = append(types, graphType{name: method.ReceiverName})
+		}
+		types[i].methods = append(types[i].methods, method)
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		return types[i].name < types[j].name
+	})
+	return types
+}
+
+// keys returns the keys of all methods in the graph, sorted
+func (cg *CallGraph) keys() []string {
+	keys := make([]string, 0, len(cg.methods))
+	for key := range cg.methods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// callees returns the distinct methods called by the method key, sorted
+func (cg *CallGraph) callees(key string) []string {
+	seen := make(map[string]bool)
+	var callees []string
+	for _, to := range cg.calls[key] {
+		if !seen[to] {
+			seen[to] = true
+			callees = append(callees, to)
+		}
+	}
+	sort.Strings(callees)
+	return callees
+}
+
+func methodLabel(method *MethodInfo, newline string) string {
+	return fmt.Sprintf("%s%sin: %d, depth: %d", method.Name, newline, method.InDegree, method.MaxDepth)
+}
@@ -0,0 +1,131 @@
+package sorter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const graphSource = `package test
+
+type Server struct{}
+
+func (s *Server) Start() error {
+	s.connect()
+	return nil
+}
+
+func (s *Server) connect() {}
+
+type Client struct{}
+
+func (c *Client) Do() {}
+`
+
+func newTestGraph(t *testing.T) *CallGraph {
+	t.Helper()
+	sorter, err := NewFromSource(graphSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sorter.CallGraph()
+}
+
+func TestCallGraphWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestGraph(t).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph calls {
+	node [shape=box];
+	subgraph cluster_0 {
+		label="Client";
+		"Client.Do" [label="Do\nin: 0, depth: 0"];
+	}
+	subgraph cluster_1 {
+		label="Server";
+		"Server.Start" [label="Start\nin: 0, depth: 1"];
+		"Server.connect" [label="connect\nin: 1, depth: 0"];
+	}
+	"Server.Start" -> "Server.connect";
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestCallGraphWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestGraph(t).WriteMermaid(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `flowchart TD
+	subgraph t0["Client"]
+		m0["Do<br/>in: 0, depth: 0"]
+	end
+	subgraph t1["Server"]
+		m1["Start<br/>in: 0, depth: 1"]
+		m2["connect<br/>in: 1, depth: 0"]
+	end
+	m1 --> m2
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestCallGraphMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(newTestGraph(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"types":[` +
+		`{"name":"Client","methods":[{"name":"Do","exported":true,"in_degree":0,"max_depth":0,"calls":[]}]},` +
+		`{"name":"Server","methods":[` +
+		`{"name":"Start","exported":true,"in_degree":0,"max_depth":1,"calls":["Server.connect"]},` +
+		`{"name":"connect","exported":false,"in_degree":1,"max_depth":0,"calls":[]}]}]}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestCallGraphForType(t *testing.T) {
+	graph := newTestGraph(t).ForType("Server")
+	if graph.Len() != 2 {
+		t.Fatalf("Expected 2 methods of Server, got %d", graph.Len())
+	}
+	if callees := graph.callees("Server.Start"); len(callees) != 1 || callees[0] != "Server.connect" {
+		t.Errorf("Expected Start to call connect, got %v", callees)
+	}
+
+	if empty := newTestGraph(t).ForType("Missing"); empty.Len() != 0 {
+		t.Errorf("Expected no methods for an unknown type, got %d", empty.Len())
+	}
+}
+
+func TestSorterCallGraphUsesPackageGraph(t *testing.T) {
+	sorters := map[string]*Sorter{}
+	for name, source := range map[string]string{
+		"a.go": "package test\n\ntype Server struct{}\n\nfunc (s *Server) Start() { s.run() }\n",
+		"b.go": "package test\n\nfunc (s *Server) run() {}\n",
+	} {
+		sorter, err := NewFromSource(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorters[name] = sorter
+	}
+	LinkPackage(sorters)
+
+	graph := sorters["a.go"].CallGraph()
+	if graph.Len() != 2 {
+		t.Fatalf("Expected the package graph with 2 methods, got %d", graph.Len())
+	}
+	if run := graph.methods["Server.run"]; run.InDegree != 1 {
+		t.Errorf("Expected run to be called from the other file, got in-degree %d", run.InDegree)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 
 	"github.com/dave/dst"
+
+	"github.com/borovikovd/gomsort/pkg/config"
 )
 
 type MethodInfo struct {
@@ -15,6 +17,9 @@ type MethodInfo struct {
 	Position     int
 	InDegree     int
 	MaxDepth     int
+
+	// id is the method's key in the call graph it was added to
+	id string
 }
 
 type MethodSortKey struct {
@@ -35,6 +40,19 @@ func (m *MethodInfo) SortKey() MethodSortKey {
 	}
 }
 
+// key identifies the method in its call graph
+func (m *MethodInfo) key() string {
+	if m.id != "" {
+		return m.id
+	}
+	return methodKey(m.ReceiverName, m.Name)
+}
+
+// isBlank reports whether the method is named _, so can never be called
+func (m *MethodInfo) isBlank() bool {
+	return m.Name == "_"
+}
+
 func extractMethodInfo(decl *dst.FuncDecl, position int) *MethodInfo {
 	if decl.Recv == nil || len(decl.Recv.List) == 0 {
 		return nil
@@ -49,33 +67,101 @@ func extractMethodInfo(decl *dst.FuncDecl, position int) *MethodInfo {
 
 	recv := decl.Recv.List[0]
 
-	switch recvType := recv.Type.(type) {
-	case *dst.Ident:
-		method.ReceiverType = recvType.Name
-		method.ReceiverName = recvType.Name
-	case *dst.StarExpr:
-		if ident, ok := recvType.X.(*dst.Ident); ok {
-			method.ReceiverType = "*" + ident.Name
-			method.ReceiverName = ident.Name
+	if name, pointer := typeName(recv.Type); name != "" {
+		method.ReceiverName = name
+		method.ReceiverType = name
+		if pointer {
+			method.ReceiverType = "*" + name
 		}
 	}
 
 	return method
 }
 
+// typeName returns the name of a T or *T type expression, where T may be
+// an instantiated generic type such as List[T] or Map[K, V]
+func typeName(expr dst.Expr) (string, bool) {
+	// Receiver types may be parenthesized, as in (*T) or *(T)
+	expr = unparen(expr)
+	pointer := false
+	if star, ok := expr.(*dst.StarExpr); ok {
+		expr = unparen(star.X)
+		pointer = true
+	}
+
+	switch t := expr.(type) {
+	case *dst.IndexExpr:
+		expr = t.X
+	case *dst.IndexListExpr:
+		expr = t.X
+	}
+
+	if ident, ok := expr.(*dst.Ident); ok {
+		return ident.Name, pointer
+	}
+	return "", false
+}
+
+func unparen(expr dst.Expr) dst.Expr {
+	for {
+		paren, ok := expr.(*dst.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// constructorType returns the type built by a NewX-style function: one that
+// returns T or *T, optionally followed by an error
+func constructorType(decl *dst.FuncDecl) string {
+	if decl.Recv != nil || decl.Type.Results == nil {
+		return ""
+	}
+
+	if !strings.HasPrefix(decl.Name.Name, "New") && !strings.HasPrefix(decl.Name.Name, "new") {
+		return ""
+	}
+
+	// Named results like (s *Server, err error) list one type per name
+	var results []dst.Expr
+	for _, field := range decl.Type.Results.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			results = append(results, field.Type)
+		}
+	}
+
+	if len(results) == 0 || len(results) > 2 {
+		return ""
+	}
+
+	if len(results) == 2 {
+		if ident, ok := results[1].(*dst.Ident); !ok || ident.Name != "error" {
+			return ""
+		}
+	}
+
+	name, _ := typeName(results[0])
+	return name
+}
+
 // Helper function since DST doesn't have ast.IsExported
 func isExported(name string) bool {
 	return len(name) > 0 && name[0] >= 'A' && name[0] <= 'Z'
 }
 
-func sortMethods(methods []*MethodInfo) []*MethodInfo {
+func sortMethods(methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
 	sorted := make([]*MethodInfo, len(methods))
 	copy(sorted, methods)
 
 	// Use bubble sort for consistency with existing implementation
 	for i := 0; i < len(sorted)-1; i++ {
 		for j := 0; j < len(sorted)-i-1; j++ {
-			if shouldSwap(sorted[j], sorted[j+1]) {
+			if shouldSwap(sorted[j], sorted[j+1], criteria) {
 				sorted[j], sorted[j+1] = sorted[j+1], sorted[j]
 			}
 		}
@@ -84,25 +170,76 @@ func sortMethods(methods []*MethodInfo) []*MethodInfo {
 	return sorted
 }
 
-func shouldSwap(a, b *MethodInfo) bool {
-	keyA := a.SortKey()
-	keyB := b.SortKey()
+// Criterion identifies the sort rule that orders two methods
+type Criterion int
+
+const (
+	CriterionNone Criterion = iota
+	CriterionReceiver
+	CriterionExported
+	CriterionDepth
+	CriterionInDegree
+	CriterionPosition
+	CriterionCallOrder
+	CriterionStrategy
+)
 
-	if keyA.ReceiverName != keyB.ReceiverName {
-		return strings.Compare(keyA.ReceiverName, keyB.ReceiverName) > 0
+func (c Criterion) String() string {
+	switch c {
+	case CriterionReceiver:
+		return "receiver"
+	case CriterionExported:
+		return "exported"
+	case CriterionDepth:
+		return "depth"
+	case CriterionInDegree:
+		return "in-degree"
+	case CriterionPosition:
+		return "position"
+	case CriterionCallOrder:
+		return "call-order"
+	case CriterionStrategy:
+		return "strategy"
 	}
+	return "none"
+}
 
-	if keyA.IsExported != keyB.IsExported {
-		return !keyA.IsExported
+// decidingCriterion returns the first enabled criterion on which the two keys
+// differ, or CriterionNone if they tie on all of them
+func decidingCriterion(a, b MethodSortKey, criteria config.SortCriteria) Criterion {
+	switch {
+	case criteria.GroupByReceiver && a.ReceiverName != b.ReceiverName:
+		return CriterionReceiver
+	case criteria.ExportedFirst && a.IsExported != b.IsExported:
+		return CriterionExported
+	case criteria.SortByDepth && a.MaxDepth != b.MaxDepth:
+		return CriterionDepth
+	case criteria.SortByInDegree && a.InDegree != b.InDegree:
+		return CriterionInDegree
+	case criteria.PreserveOrigOrder && a.OriginalPos != b.OriginalPos:
+		return CriterionPosition
 	}
+	return CriterionNone
+}
 
-	if keyA.MaxDepth != keyB.MaxDepth {
-		return keyA.MaxDepth > keyB.MaxDepth
-	}
+func shouldSwap(a, b *MethodInfo, criteria config.SortCriteria) bool {
+	keyA := a.SortKey()
+	keyB := b.SortKey()
 
-	if keyA.InDegree != keyB.InDegree {
+	switch decidingCriterion(keyA, keyB, criteria) {
+	case CriterionReceiver:
+		return strings.Compare(keyA.ReceiverName, keyB.ReceiverName) > 0
+	case CriterionExported:
+		return !keyA.IsExported
+	case CriterionDepth:
+		return keyA.MaxDepth > keyB.MaxDepth
+	case CriterionInDegree:
 		return keyA.InDegree < keyB.InDegree
+	case CriterionPosition:
+		return keyA.OriginalPos > keyB.OriginalPos
 	}
 
-	return keyA.OriginalPos > keyB.OriginalPos
+	// Bubble sort is stable, so without a deciding criterion equal keys
+	// simply keep the order they already have
+	return false
 }
@@ -5,6 +5,8 @@ import (
 
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
+
+	"github.com/borovikovd/gomsort/pkg/config"
 )
 
 func TestMethodSortKey(t *testing.T) {
@@ -126,6 +128,57 @@ func NotAMethod() {}
 	}
 }
 
+func TestExtractMethodInfoGenerics(t *testing.T) {
+	source := `package test
+
+type List[T any] struct{}
+type Map[K comparable, V any] struct{}
+type Triple[A, B, C any] struct{}
+
+func (l *List[T]) Push(v T) {}
+func (l List[T]) Len() int { return 0 }
+func (m Map[K, V]) Get(k K) V { var v V; return v }
+func (m *Map[K, V]) set(k K, v V) {}
+func (t *Triple[A, B, C]) First() A { var a A; return a }
+func (_ *List[_]) reset() {}
+`
+
+	file, err := decorator.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]struct {
+		receiverName string
+		receiverType string
+	}{
+		"Push":  {"List", "*List"},
+		"Len":   {"List", "List"},
+		"Get":   {"Map", "Map"},
+		"set":   {"Map", "*Map"},
+		"First": {"Triple", "*Triple"},
+		"reset": {"List", "*List"},
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*dst.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		method := extractMethodInfo(funcDecl, 0)
+		if method == nil {
+			t.Fatalf("Expected method info for %s", funcDecl.Name.Name)
+		}
+
+		want := expected[method.Name]
+		if method.ReceiverName != want.receiverName || method.ReceiverType != want.receiverType {
+			t.Errorf("%s: got receiver %s (%s), want %s (%s)",
+				method.Name, method.ReceiverName, method.ReceiverType, want.receiverName, want.receiverType)
+		}
+	}
+}
+
 func TestShouldSwap(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -167,7 +220,7 @@ func TestShouldSwap(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := shouldSwap(tt.a, tt.b)
+			result := shouldSwap(tt.a, tt.b, config.DefaultConfig().SortCriteria)
 			if result != tt.expected {
 				t.Errorf("shouldSwap() = %v, want %v", result, tt.expected)
 			}
@@ -183,7 +236,7 @@ func TestSortMethods(t *testing.T) {
 		{Name: "internal", ReceiverName: "Client", IsExported: false, MaxDepth: 0, InDegree: 1, Position: 400},
 	}
 
-	sorted := sortMethods(methods)
+	sorted := sortMethods(methods, config.DefaultConfig().SortCriteria)
 
 	expectedOrder := []string{"Connect", "internal", "Start", "helper"}
 	for i, expected := range expectedOrder {
@@ -192,3 +245,128 @@ func TestSortMethods(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldSwapWithDisabledCriteria(t *testing.T) {
+	exported := &MethodInfo{ReceiverName: "Server", IsExported: true, MaxDepth: 2, InDegree: 0, Position: 200}
+	private := &MethodInfo{ReceiverName: "Server", IsExported: false, MaxDepth: 0, InDegree: 0, Position: 100}
+
+	criteria := config.DefaultConfig().SortCriteria
+	if !shouldSwap(private, exported, criteria) {
+		t.Error("Expected exported method to be moved before private method by default")
+	}
+
+	criteria.ExportedFirst = false
+	if shouldSwap(private, exported, criteria) {
+		t.Error("Expected lower depth to win once exported_first is disabled")
+	}
+
+	criteria.SortByDepth = false
+	if shouldSwap(private, exported, criteria) {
+		t.Error("Expected original position to decide once sort_by_depth is disabled")
+	}
+
+	criteria.PreserveOrigOrder = false
+	if shouldSwap(exported, private, criteria) {
+		t.Error("Expected no swap when every criterion is disabled")
+	}
+}
+
+func TestSortMethodsWithoutReceiverGrouping(t *testing.T) {
+	methods := []*MethodInfo{
+		{Name: "helper", ReceiverName: "Server", IsExported: false, Position: 0},
+		{Name: "Start", ReceiverName: "Server", IsExported: true, Position: 1},
+		{Name: "Connect", ReceiverName: "Client", IsExported: true, Position: 2},
+	}
+
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.GroupByReceiver = false
+
+	sorted := sortMethods(methods, criteria)
+
+	expectedOrder := []string{"Start", "Connect", "helper"}
+	for i, expected := range expectedOrder {
+		if sorted[i].Name != expected {
+			t.Errorf("Position %d: expected %s, got %s", i, expected, sorted[i].Name)
+		}
+	}
+}
+
+func TestConstructorType(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func NewServer() *Server { return nil }
+func NewServerValue() Server { return Server{} }
+func NewServerWithError(addr string) (*Server, error) { return nil, nil }
+func newServer() (s *Server, err error) { return nil, nil }
+func NewServerPair() (*Server, *Server) { return nil, nil }
+func NewServers() []*Server { return nil }
+func BuildServer() *Server { return nil }
+func NewNothing() {}
+func (s *Server) NewChild() *Server { return nil }
+
+type List[T any] struct{}
+type Map[K comparable, V any] struct{}
+
+func NewList[T any]() *List[T] { return nil }
+func NewMap[K comparable, V any]() (Map[K, V], error) { return Map[K, V]{}, nil }
+`
+
+	file, err := decorator.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"NewServer":          "Server",
+		"NewServerValue":     "Server",
+		"NewServerWithError": "Server",
+		"newServer":          "Server",
+		"NewServerPair":      "",
+		"NewServers":         "",
+		"BuildServer":        "",
+		"NewNothing":         "",
+		"NewChild":           "",
+		"NewList":            "List",
+		"NewMap":             "Map",
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*dst.FuncDecl)
+		if !ok {
+			continue
+		}
+		if result := constructorType(funcDecl); result != expected[funcDecl.Name.Name] {
+			t.Errorf("constructorType(%s) = %q, want %q", funcDecl.Name.Name, result, expected[funcDecl.Name.Name])
+		}
+	}
+}
+
+func TestDecidingCriterion(t *testing.T) {
+	criteria := config.DefaultConfig().SortCriteria
+	base := MethodSortKey{ReceiverName: "Server", IsExported: true, MaxDepth: 1, InDegree: 1, OriginalPos: 0}
+
+	tests := []struct {
+		name     string
+		modify   func(*MethodSortKey)
+		expected Criterion
+	}{
+		{"receiver", func(k *MethodSortKey) { k.ReceiverName = "Client"; k.IsExported = false }, CriterionReceiver},
+		{"exported", func(k *MethodSortKey) { k.IsExported = false; k.MaxDepth = 0 }, CriterionExported},
+		{"depth", func(k *MethodSortKey) { k.MaxDepth = 2; k.InDegree = 0 }, CriterionDepth},
+		{"in-degree", func(k *MethodSortKey) { k.InDegree = 3 }, CriterionInDegree},
+		{"position", func(k *MethodSortKey) { k.OriginalPos = 1 }, CriterionPosition},
+		{"none", func(k *MethodSortKey) {}, CriterionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.modify(&other)
+			if result := decidingCriterion(base, other, criteria); result != tt.expected {
+				t.Errorf("decidingCriterion() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package sorter
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+	"github.com/dave/dst"
+)
+
+// ParseDir parses the non-test Go files in dir that settings selects (all of
+// them if settings is nil) and returns a sorter for each, keyed by absolute
+// file name. The files of each package are linked with LinkPackage. Files
+// that cannot be read or parsed are left out, so that they fail on their own
+// when sorted rather than failing the whole directory.
+func ParseDir(dir string, settings *config.Config) (map[string]*Sorter, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	sorters := make(map[string]*Sorter)
+	packages := make(map[string]map[string]*Sorter)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		filename, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if settings != nil && !settings.ShouldProcess(filename) {
+			continue
+		}
+
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		sorter, err := NewFromFile(fset, file, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decorating %s: %w", filename, err)
+		}
+		sorters[filename] = sorter
+
+		pkgName := sorter.file.Name.Name
+		if packages[pkgName] == nil {
+			packages[pkgName] = make(map[string]*Sorter)
+		}
+		packages[pkgName][filename] = sorter
+	}
+
+	for _, pkg := range packages {
+		LinkPackage(pkg)
+	}
+
+	return sorters, nil
+}
+
+// LinkPackage makes the sorters, which must hold the files of one package
+// keyed by file name, order methods by call depth and in-degree computed
+// across all the files rather than within each file
+func LinkPackage(sorters map[string]*Sorter) {
+	files := make([]*dst.File, 0, len(sorters))
+	astNodes := make(map[dst.Node]ast.Node)
+	var info *types.Info
+	typed := len(sorters) > 0
+	for _, s := range sortedSorters(sorters) {
+		files = append(files, s.file)
+		if s.typesInfo == nil {
+			typed = false
+			continue
+		}
+		info = s.typesInfo
+		for node, astNode := range s.astNodes {
+			astNodes[node] = astNode
+		}
+	}
+
+	var graph *CallGraph
+	if typed {
+		graph = buildTypedCallGraph(files, astNodes, info)
+	} else {
+		graph = buildCallGraph(files...)
+	}
+
+	for _, s := range sorters {
+		s.packageGraph = graph
+	}
+}
+
+// copyMetrics replaces the metrics of a single file's methods with the ones
+// computed for the same methods in this graph
+func (cg *CallGraph) copyMetrics(methods []*MethodInfo) {
+	for _, method := range methods {
+		if global, ok := cg.methods[methodKey(method.ReceiverName, method.Name)]; ok {
+			method.InDegree = global.InDegree
+			method.MaxDepth = global.MaxDepth
+		}
+	}
+}
+
+// sortedSorters returns the sorters ordered by file name, so that the
+// package graph does not depend on map iteration order
+func sortedSorters(sorters map[string]*Sorter) []*Sorter {
+	names := make([]string, 0, len(sorters))
+	for name := range sorters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]*Sorter, 0, len(names))
+	for _, name := range names {
+		result = append(result, sorters[name])
+	}
+	return result
+}
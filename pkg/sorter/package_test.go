@@ -0,0 +1,156 @@
+package sorter
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+var packageTestFiles = map[string]string{
+	"server.go": `package test
+
+type Server struct{}
+
+func (s *Server) run() {
+	s.dispatch()
+}
+
+func (s *Server) helper() {}
+
+func (s *Server) close() {}
+`,
+	"server_http.go": `package test
+
+func (s *Server) dispatch() {
+	s.helper()
+}
+`,
+	"server_test.go": `package test
+
+func (s *Server) broken(
+`,
+}
+
+func TestParseDirLinksPackage(t *testing.T) {
+	dir := writeTestPackage(t, packageTestFiles)
+
+	sorters, err := ParseDir(dir, nil)
+	if err != nil {
+		t.Fatalf("ParseDir() failed: %v", err)
+	}
+	if len(sorters) != 2 {
+		t.Fatalf("Expected sorters for the 2 non-test files, got %d", len(sorters))
+	}
+
+	filename, err := filepath.Abs(filepath.Join(dir, "server.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := sorters[filename]
+	graph := s.buildCallGraph()
+	methods := graph.GetMethods()
+	s.packageGraph.copyMetrics(methods)
+
+	expected := map[string]struct {
+		inDegree int
+		maxDepth int
+	}{
+		"run":    {0, 2},
+		"helper": {1, 0},
+		"close":  {0, 0},
+	}
+	for _, method := range methods {
+		want := expected[method.Name]
+		if method.InDegree != want.inDegree || method.MaxDepth != want.maxDepth {
+			t.Errorf("%s: InDegree=%d MaxDepth=%d, want InDegree=%d MaxDepth=%d",
+				method.Name, method.InDegree, method.MaxDepth, want.inDegree, want.maxDepth)
+		}
+	}
+}
+
+func TestParseDirSkipsFiles(t *testing.T) {
+	files := map[string]string{
+		"zz_broken.go":  "package test\n\nfunc broken( {\n",
+		"server_gen.go": "package test\n\nfunc (s *Server) generated() {}\n",
+	}
+	for name, content := range packageTestFiles {
+		files[name] = content
+	}
+	dir := writeTestPackage(t, files)
+
+	settings := config.DefaultConfig()
+	settings.Exclude = []string{"*_gen.go"}
+	sorters, err := ParseDir(dir, settings)
+	if err != nil {
+		t.Fatalf("ParseDir() failed: %v", err)
+	}
+
+	var names []string
+	for filename := range sorters {
+		names = append(names, filepath.Base(filename))
+	}
+	sort.Strings(names)
+	expected := []string{"server.go", "server_http.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected sorters for %v, got %v", expected, names)
+	}
+}
+
+func TestSortWithPackageMetrics(t *testing.T) {
+	dir := writeTestPackage(t, packageTestFiles)
+	filename, err := filepath.Abs(filepath.Join(dir, "server.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// On its own the file looks sorted: nothing it calls is declared in it
+	single, err := NewFromSource(packageTestFiles["server.go"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err := single.Sort(); err != nil || changed {
+		t.Fatalf("Expected single-file sort to be a no-op, changed=%v err=%v", changed, err)
+	}
+
+	expected := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) close() {}
+
+func (s *Server) run() {
+	s.dispatch()
+}
+`
+
+	sorters, err := ParseDir(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The broken test file is not part of the package build either way
+	typed, err := LoadPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	LinkPackage(typed)
+
+	for name, s := range map[string]*Sorter{"parsed": sorters[filename], "typed": typed[filename]} {
+		sorted, changed, err := s.Sort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed {
+			t.Errorf("%s: expected package metrics to reorder methods", name)
+		}
+		if string(sorted) != expected {
+			t.Errorf("%s: unexpected result.\nExpected:\n%s\nGot:\n%s", name, expected, sorted)
+		}
+	}
+}
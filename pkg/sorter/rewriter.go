@@ -2,37 +2,135 @@ package sorter
 
 import (
 	"bytes"
-	"os"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
 
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
+
+	"github.com/borovikovd/gomsort/pkg/config"
 )
 
 type Sorter struct {
-	source string
-	file   *dst.File
+	source   string
+	file     *dst.File
+	criteria config.SortCriteria
+
+	// Set by SetStrategy, which overrides criteria.Strategy
+	strategy Strategy
+
+	// Set for sorters built from parsed files: astNodes maps back to the
+	// original syntax, and typesInfo is set if the file was type-checked
+	fset      *token.FileSet
+	typesInfo *types.Info
+	astNodes  map[dst.Node]ast.Node
+
+	// Set by LinkPackage to the call graph of the whole package
+	packageGraph *CallGraph
+
+	// In debug mode Sort checks that its output is a fixed point
+	debug bool
+
+	moves      []Move
+	misplaced  []Misplacement
+	placements []Placement
+}
+
+// ErrNotIdempotent is returned by Sort in debug mode when sorting its own
+// output again would change it
+var ErrNotIdempotent = errors.New("sorting is not idempotent")
+
+// Move records a method whose index among the file's methods changed
+type Move struct {
+	ReceiverName string
+	Name         string
+	From         int
+	To           int
 }
 
 func NewFromSource(source string) (*Sorter, error) {
-	file, err := decorator.Parse(source)
+	// dst goes on to decorate files that failed to parse, and panics on
+	// some of them, so parse first
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := decorator.NewDecorator(fset).DecorateFile(astFile)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Sorter{
-		source: source,
-		file:   file,
+		source:   source,
+		file:     file,
+		criteria: config.DefaultConfig().SortCriteria,
 	}, nil
 }
 
-func WriteFile(filename string, content []byte) error {
-	return os.WriteFile(filename, content, 0644)
+// NewFromFile returns a sorter for a file that is already parsed, as in
+// analysis passes. The file is left untouched, and positions reported by the
+// sorter refer to it. With info, calls are resolved through the type checker
+// instead of guessed from receiver names.
+func NewFromFile(fset *token.FileSet, file *ast.File, info *types.Info) (*Sorter, error) {
+	dec := decorator.NewDecorator(fset)
+	dstFile, err := dec.DecorateFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sorter{
+		file:      dstFile,
+		criteria:  config.DefaultConfig().SortCriteria,
+		fset:      fset,
+		typesInfo: info,
+		astNodes:  dec.Ast.Nodes,
+	}, nil
+}
+
+// Position resolves a position reported by the sorter, such as
+// Placement.Pos. It is the zero Position for sorters built from source.
+func (s *Sorter) Position(pos token.Pos) token.Position {
+	if s.fset == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return s.fset.Position(pos)
+}
+
+func (s *Sorter) SetCriteria(criteria config.SortCriteria) {
+	s.criteria = criteria
+}
+
+// SetStrategy makes Sort order methods with strategy, whatever the strategy
+// named in the criteria
+func (s *Sorter) SetStrategy(strategy Strategy) {
+	s.strategy = strategy
+}
+
+// SetDebug turns on checking of the sorter's own results. In debug mode
+// Sort sorts its output a second time and fails with ErrNotIdempotent if
+// that would move anything.
+func (s *Sorter) SetDebug(debug bool) {
+	s.debug = debug
 }
 
 func (s *Sorter) Sort() ([]byte, bool, error) {
-	callGraph := buildCallGraph(s.file)
+	callGraph := s.buildCallGraph()
 	methods := callGraph.GetMethods()
 
+	// Methods are ordered by the package's graph where there is one; the
+	// file's methods are the same in both
+	graph := callGraph
+	if s.packageGraph != nil {
+		s.packageGraph.copyMetrics(methods)
+		graph = s.packageGraph
+	}
+
 	if len(methods) == 0 {
 		// No methods to sort, just return formatted source
 		var buf bytes.Buffer
@@ -42,9 +140,34 @@ func (s *Sorter) Sort() ([]byte, bool, error) {
 		return buf.Bytes(), false, nil
 	}
 
-	sortedMethods := sortMethods(methods)
+	strategy := s.strategy
+	if strategy == nil {
+		var err error
+		if strategy, err = LookupStrategy(s.criteria.Strategy); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// The strategy gets a copy, which it may reorder in place
+	sortedMethods := strategy.Order(graph, append([]*MethodInfo(nil), methods...), s.criteria)
+	if err := checkOrder(methods, sortedMethods); err != nil {
+		return nil, false, err
+	}
+	constructors := s.findConstructors(methods)
+	newDecls := s.arrangeDecls(sortedMethods, constructors)
+	s.placements = methodPlacements(methodOrder(methods, newDecls), strategy, s.criteria, s.astNodes)
+
+	// At the end of the file only the relative order of the moved
+	// declarations matters, while the after-type layout also moves them
+	// relative to everything else
+	moved := movedDecls(sortedMethods, constructors)
+	var changed bool
+	if s.criteria.Layout == config.LayoutAfterType {
+		changed = !sameDecls(s.file.Decls, newDecls)
+	} else {
+		changed = !sameDecls(filterDecls(s.file.Decls, moved), filterDecls(newDecls, moved))
+	}
 
-	changed := s.hasOrderChanged(methods, sortedMethods)
 	if !changed {
 		// No changes needed, return formatted source
 		var buf bytes.Buffer
@@ -54,8 +177,18 @@ func (s *Sorter) Sort() ([]byte, bool, error) {
 		return buf.Bytes(), false, nil
 	}
 
+	trailing := attachDetachedComments(s.file.Decls, moved)
+	if s.criteria.StrictComments {
+		if err := checkCommentOwnership(s.file.Decls, newDecls, moved); err != nil {
+			return nil, false, err
+		}
+	}
+
 	// Reorder methods in DST - decorations will move automatically
-	s.reorderMethods(sortedMethods)
+	s.moves = methodMoves(methods, newDecls)
+	s.misplaced = misplacedMethods(methodOrder(methods, newDecls), strategy, s.criteria, s.astNodes)
+	s.file.Decls = newDecls
+	restoreTrailingComments(s.file.Decls, trailing)
 
 	// Format with DST
 	var buf bytes.Buffer
@@ -63,47 +196,210 @@ func (s *Sorter) Sort() ([]byte, bool, error) {
 		return nil, true, err
 	}
 
+	if s.debug {
+		if err := checkIdempotent(buf.Bytes(), strategy, s.criteria, graph); err != nil {
+			return nil, true, err
+		}
+	}
+
 	return buf.Bytes(), true, nil
 }
 
-func (s *Sorter) hasOrderChanged(original, sorted []*MethodInfo) bool {
-	if len(original) != len(sorted) {
-		return true
+// checkIdempotent sorts output again with the call graph it was sorted by,
+// since the reparsed file alone only yields the heuristic one
+func checkIdempotent(output []byte, strategy Strategy, criteria config.SortCriteria, graph *CallGraph) error {
+	again, err := NewFromSource(string(output))
+	if err != nil {
+		return fmt.Errorf("%w: output does not parse: %v", ErrNotIdempotent, err)
 	}
+	again.SetCriteria(criteria)
+	again.SetStrategy(strategy)
+	again.packageGraph = graph
 
-	for i, method := range original {
-		if method.Position != sorted[i].Position {
-			return true
+	_, changed, err := again.Sort()
+	if err != nil {
+		return fmt.Errorf("%w: sorting the output failed: %v", ErrNotIdempotent, err)
+	}
+	if !changed {
+		return nil
+	}
+
+	for _, move := range again.Moves() {
+		name := move.Name
+		if move.ReceiverName != "" {
+			name = move.ReceiverName + "." + move.Name
 		}
+		return fmt.Errorf("%w: sorting the output again moves %s from %d to %d", ErrNotIdempotent, name, move.From, move.To)
 	}
+	return fmt.Errorf("%w: sorting the output again changes it", ErrNotIdempotent)
+}
 
-	return false
+// Moves returns the methods moved by the last call to Sort, in their
+// original order
+func (s *Sorter) Moves() []Move {
+	return s.moves
 }
 
-func (s *Sorter) reorderMethods(sortedMethods []*MethodInfo) {
-	// Create method lookup map
-	methodMap := make(map[*dst.FuncDecl]bool)
-	for _, method := range sortedMethods {
-		methodMap[method.FuncDecl] = true
+func (s *Sorter) buildCallGraph() *CallGraph {
+	if s.typesInfo != nil {
+		return buildTypedCallGraph([]*dst.File{s.file}, s.astNodes, s.typesInfo)
+	}
+	return buildCallGraph(s.file)
+}
+
+// findConstructors returns the constructors that travel with each type, in
+// their original order. At the end of the file they only attach to types
+// that have methods here; after a type declaration they always do.
+func (s *Sorter) findConstructors(methods []*MethodInfo) map[string][]dst.Decl {
+	constructors := make(map[string][]dst.Decl)
+	if !s.criteria.KeepConstructors {
+		return constructors
+	}
+
+	targets := make(map[string]bool)
+	for _, method := range methods {
+		targets[method.ReceiverName] = true
+	}
+	if s.criteria.Layout == config.LayoutAfterType {
+		for _, decl := range s.file.Decls {
+			for _, name := range declaredTypes(decl) {
+				targets[name] = true
+			}
+		}
 	}
 
-	// Collect non-method declarations first
+	for _, decl := range s.file.Decls {
+		funcDecl, ok := decl.(*dst.FuncDecl)
+		if !ok {
+			continue
+		}
+		if name := constructorType(funcDecl); name != "" && targets[name] {
+			constructors[name] = append(constructors[name], funcDecl)
+		}
+	}
+
+	return constructors
+}
+
+func (s *Sorter) arrangeDecls(sortedMethods []*MethodInfo, constructors map[string][]dst.Decl) []dst.Decl {
+	moved := movedDecls(sortedMethods, constructors)
+
+	// Collect the declarations that stay in place first
 	newDecls := make([]dst.Decl, 0, len(s.file.Decls))
+	placed := make(map[string]bool)
 	for _, decl := range s.file.Decls {
-		if funcDecl, ok := decl.(*dst.FuncDecl); ok {
-			// Skip methods - we'll add them in sorted order
-			if methodMap[funcDecl] {
+		// Skip methods and constructors - we'll add them in sorted order
+		if moved[decl] {
+			continue
+		}
+		newDecls = append(newDecls, decl)
+
+		if s.criteria.Layout != config.LayoutAfterType {
+			continue
+		}
+
+		// A grouped type ( ... ) declaration gets the blocks of all its
+		// types, in the order the types are declared. A type declared twice
+		// does not compile, but must not get its methods twice either.
+		for _, name := range declaredTypes(decl) {
+			if placed[name] {
 				continue
 			}
+			newDecls = append(newDecls, constructors[name]...)
+			for _, method := range sortedMethods {
+				if method.ReceiverName == name {
+					newDecls = append(newDecls, method.FuncDecl)
+				}
+			}
+			placed[name] = true
 		}
-		newDecls = append(newDecls, decl)
 	}
 
-	// Add sorted methods - their decorations (comments) will move with them automatically
+	// Add the rest at the end, each type's constructors right before its first
+	// method - their decorations (comments) will move with them automatically
+	started := make(map[string]bool)
 	for _, method := range sortedMethods {
+		if placed[method.ReceiverName] {
+			continue
+		}
+		if !started[method.ReceiverName] {
+			newDecls = append(newDecls, constructors[method.ReceiverName]...)
+			started[method.ReceiverName] = true
+		}
 		newDecls = append(newDecls, method.FuncDecl)
 	}
 
-	// Update the DST file with reordered declarations
-	s.file.Decls = newDecls
+	return newDecls
+}
+
+func movedDecls(sortedMethods []*MethodInfo, constructors map[string][]dst.Decl) map[dst.Decl]bool {
+	moved := make(map[dst.Decl]bool)
+	for _, method := range sortedMethods {
+		moved[method.FuncDecl] = true
+	}
+	for _, decls := range constructors {
+		for _, decl := range decls {
+			moved[decl] = true
+		}
+	}
+	return moved
+}
+
+func filterDecls(decls []dst.Decl, keep map[dst.Decl]bool) []dst.Decl {
+	filtered := make([]dst.Decl, 0, len(keep))
+	for _, decl := range decls {
+		if keep[decl] {
+			filtered = append(filtered, decl)
+		}
+	}
+	return filtered
+}
+
+func declaredTypes(decl dst.Decl) []string {
+	genDecl, ok := decl.(*dst.GenDecl)
+	if !ok || genDecl.Tok != token.TYPE {
+		return nil
+	}
+
+	names := make([]string, 0, len(genDecl.Specs))
+	for _, spec := range genDecl.Specs {
+		if typeSpec, ok := spec.(*dst.TypeSpec); ok {
+			names = append(names, typeSpec.Name.Name)
+		}
+	}
+	return names
+}
+
+func methodMoves(methods []*MethodInfo, newDecls []dst.Decl) []Move {
+	newIndex := make(map[*MethodInfo]int, len(methods))
+	for i, method := range methodOrder(methods, newDecls) {
+		newIndex[method] = i
+	}
+
+	var moves []Move
+	for _, method := range methods {
+		if to := newIndex[method]; to != method.Position {
+			moves = append(moves, Move{
+				ReceiverName: method.ReceiverName,
+				Name:         method.Name,
+				From:         method.Position,
+				To:           to,
+			})
+		}
+	}
+	return moves
+}
+
+func sameDecls(a, b []dst.Decl) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
 }
@@ -1,11 +1,16 @@
 package sorter
 
 import (
+	"errors"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
 )
 
 func TestSorterIntegration(t *testing.T) {
@@ -825,3 +830,667 @@ func (s *Server) Start() error {
 		t.Error("Expected non-empty result")
 	}
 }
+
+func TestSorterAfterTypeLayout(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+type (
+	Client struct{}
+	Pool   struct{}
+)
+
+func helper() {}
+
+func (c *Client) dial() {}
+
+func (s *Server) Start() {}
+
+func (c *Client) Connect() {}
+
+func (p *Pool) Get() {}
+
+func (x *External) Run() {}
+`
+
+	expected := `package test
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+type (
+	Client struct{}
+	Pool   struct{}
+)
+
+func (c *Client) Connect() {}
+
+func (c *Client) dial() {}
+
+func (p *Pool) Get() {}
+
+func helper() {}
+
+func (x *External) Run() {}
+`
+
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Layout = config.LayoutAfterType
+	sorter.SetCriteria(criteria)
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected methods to be moved next to their types")
+	}
+	if string(sorted) != expected {
+		t.Errorf("Unexpected layout.\nExpected:\n%s\nGot:\n%s", expected, sorted)
+	}
+
+	// The result is already laid out, so sorting it again changes nothing
+	again, err := NewFromSource(string(sorted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.SetCriteria(criteria)
+	if _, changed, err := again.Sort(); err != nil || changed {
+		t.Errorf("Expected second pass to be a no-op, changed=%v err=%v", changed, err)
+	}
+}
+
+func TestSorterKeepsConstructorsWithMethods(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+// NewServer creates a server
+func NewServer() *Server {
+	return &Server{}
+}
+
+func helper() {}
+
+func (s *Server) stop() {}
+
+func (s *Server) Start() {}
+`
+
+	tests := []struct {
+		name     string
+		layout   string
+		expected string
+	}{
+		{
+			name:   "end layout",
+			layout: config.LayoutEnd,
+			expected: `package test
+
+type Server struct{}
+
+func helper() {}
+
+// NewServer creates a server
+func NewServer() *Server {
+	return &Server{}
+}
+
+func (s *Server) Start() {}
+
+func (s *Server) stop() {}
+`,
+		},
+		{
+			name:   "after type layout",
+			layout: config.LayoutAfterType,
+			expected: `package test
+
+type Server struct{}
+
+// NewServer creates a server
+func NewServer() *Server {
+	return &Server{}
+}
+
+func (s *Server) Start() {}
+
+func (s *Server) stop() {}
+
+func helper() {}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter, err := NewFromSource(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			criteria := config.DefaultConfig().SortCriteria
+			criteria.Layout = tt.layout
+			criteria.KeepConstructors = true
+			sorter.SetCriteria(criteria)
+
+			sorted, changed, err := sorter.Sort()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !changed {
+				t.Error("Expected declarations to be reordered")
+			}
+			if string(sorted) != tt.expected {
+				t.Errorf("Unexpected result.\nExpected:\n%s\nGot:\n%s", tt.expected, sorted)
+			}
+		})
+	}
+}
+
+func TestSorterConstructorsDisabled(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+func (s *Server) Stop() {}
+
+func NewServer() *Server {
+	return &Server{}
+}
+`
+
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Constructors are left where they are by default
+	if _, changed, err := sorter.Sort(); err != nil || changed {
+		t.Errorf("Expected constructor to stay put when disabled, changed=%v err=%v", changed, err)
+	}
+
+	// With constructors enabled the trailing NewServer moves before the methods
+	sorter, err = NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.KeepConstructors = true
+	sorter.SetCriteria(criteria)
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected constructor to be moved before the methods")
+	}
+	if strings.Index(string(sorted), "func NewServer()") > strings.Index(string(sorted), "func (s *Server) Start()") {
+		t.Errorf("Expected NewServer before Start, got:\n%s", sorted)
+	}
+}
+
+func TestSorterAttachesDetachedComments(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+// Comment below the type
+
+func (s *Server) helper() {}
+// Comment below helper
+
+func (s *Server) Start() { s.helper() } // inline
+// Detached after Start
+
+var x = 1
+
+func (s *Server) Stop() {}
+// Trailing file comment
+`
+
+	expected := `package test
+
+type Server struct{}
+
+// Detached after Start
+
+var x = 1
+
+func (s *Server) Stop() {}
+
+// Comment below helper
+
+func (s *Server) Start() { s.helper() } // inline
+
+// Comment below the type
+
+func (s *Server) helper() {}
+
+// Trailing file comment
+`
+
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected methods to be reordered")
+	}
+	if string(sorted) != expected {
+		t.Errorf("Comments did not travel with their methods.\nExpected:\n%s\nGot:\n%s", expected, sorted)
+	}
+}
+
+func TestSorterComplexExampleComments(t *testing.T) {
+	source, err := os.ReadFile("../../testdata/complex_example.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorter, err := NewFromSource(string(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected complex example to be reordered")
+	}
+
+	// Every doc comment must still sit directly above its method
+	lines := strings.Split(string(source), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "// ") || !strings.HasPrefix(lines[i+1], "func ") {
+			continue
+		}
+
+		pair := lines[i] + "\n" + lines[i+1]
+		if !strings.Contains(string(sorted), pair) {
+			t.Errorf("Comment %q was separated from %q.\nSorted code:\n%s", lines[i], lines[i+1], sorted)
+		}
+	}
+
+	// The section comment is detached, so it moves along with the first method
+	sectionPattern := "// Complex example with various method types and call patterns\n\n" +
+		"// Helper method called by multiple methods (high in-degree)\nfunc (db *Database) validateConnection()"
+	if !strings.Contains(string(sorted), sectionPattern) {
+		t.Errorf("Expected section comment to travel with validateConnection.\nSorted code:\n%s", sorted)
+	}
+}
+
+func TestSorterStrictCommentsRefusesAmbiguousFile(t *testing.T) {
+	source, err := os.ReadFile("../../testdata/complex_example.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorter, err := NewFromSource(string(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.StrictComments = true
+	sorter.SetCriteria(criteria)
+
+	if _, _, err := sorter.Sort(); !errors.Is(err, ErrAmbiguousComment) {
+		t.Errorf("Expected ErrAmbiguousComment, got %v", err)
+	}
+
+	// Attached doc comments are never ambiguous
+	unambiguous := `package test
+
+type Server struct{}
+
+// helper helps
+func (s *Server) helper() {}
+
+// Start starts
+func (s *Server) Start() {}
+`
+
+	sorter, err = NewFromSource(unambiguous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorter.SetCriteria(criteria)
+
+	if _, changed, err := sorter.Sort(); err != nil || !changed {
+		t.Errorf("Expected strict mode to sort attached comments, changed=%v err=%v", changed, err)
+	}
+}
+
+func TestSorterMoves(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+
+func (s *Server) Stop() {}
+
+func (s *Server) Start() {}
+`
+
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := sorter.Sort(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Move{
+		{ReceiverName: "Server", Name: "helper", From: 0, To: 2},
+		{ReceiverName: "Server", Name: "Stop", From: 1, To: 0},
+		{ReceiverName: "Server", Name: "Start", From: 2, To: 1},
+	}
+
+	moves := sorter.Moves()
+	if len(moves) != len(expected) {
+		t.Fatalf("Moves() = %+v, want %+v", moves, expected)
+	}
+	for i := range expected {
+		if moves[i] != expected[i] {
+			t.Errorf("Moves()[%d] = %+v, want %+v", i, moves[i], expected[i])
+		}
+	}
+}
+
+func TestSorterGenericReceivers(t *testing.T) {
+	source := `package test
+
+type List[T any] struct{}
+
+type Map[K comparable, V any] struct{}
+
+func (m *Map[K, V]) set(k K, v V) {}
+
+func (l *List[T]) grow() {}
+
+func NewList[T any]() *List[T] { return &List[T]{} }
+
+func (m Map[K, V]) Get(k K) V {
+	var v V
+	return v
+}
+
+func (l *List[T]) Push(v T) {
+	l.grow()
+}
+`
+
+	expected := `package test
+
+type List[T any] struct{}
+
+func NewList[T any]() *List[T] { return &List[T]{} }
+
+func (l *List[T]) Push(v T) {
+	l.grow()
+}
+
+func (l *List[T]) grow() {}
+
+type Map[K comparable, V any] struct{}
+
+func (m Map[K, V]) Get(k K) V {
+	var v V
+	return v
+}
+
+func (m *Map[K, V]) set(k K, v V) {}
+`
+
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Layout = config.LayoutAfterType
+	criteria.KeepConstructors = true
+	sorter.SetCriteria(criteria)
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected generic methods to be sorted")
+	}
+	if string(sorted) != expected {
+		t.Errorf("Unexpected result.\nExpected:\n%s\nGot:\n%s", expected, sorted)
+	}
+}
+
+func TestNewFromFile(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+// helper is unexported
+func (s *Server) helper() {}
+
+// Start is exported
+func (s *Server) Start() {
+	s.helper()
+}
+`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "server.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorter, err := NewFromFile(fset, file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected methods to be sorted")
+	}
+	if strings.Index(string(sorted), "// Start is exported") > strings.Index(string(sorted), "// helper is unexported") {
+		t.Errorf("Expected Start and its comment first, got:\n%s", sorted)
+	}
+
+	// The parsed file keeps its order
+	if name := file.Decls[1].(*ast.FuncDecl).Name.Name; name != "helper" {
+		t.Errorf("Expected the original AST to be untouched, got %s as first method", name)
+	}
+
+	misplaced := sorter.Misplaced()
+	if len(misplaced) != 1 {
+		t.Fatalf("Expected one misplaced method, got %+v", misplaced)
+	}
+	if position := fset.Position(misplaced[0].Pos); position.Line != 9 || position.Column != 18 {
+		t.Errorf("Expected Start at server.go:9:18, got %v", position)
+	}
+}
+
+func TestSorterDebugChecksIdempotence(t *testing.T) {
+	source, err := os.ReadFile("../../testdata/complex_example.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layout := range []string{config.LayoutEnd, config.LayoutAfterType} {
+		sorter, err := NewFromSource(string(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		criteria := config.DefaultConfig().SortCriteria
+		criteria.Layout = layout
+		sorter.SetCriteria(criteria)
+		sorter.SetDebug(true)
+
+		if _, _, err := sorter.Sort(); err != nil {
+			t.Errorf("Expected the %s layout to be idempotent, got %v", layout, err)
+		}
+	}
+}
+
+func TestCheckIdempotentReportsMove(t *testing.T) {
+	// Metrics that disagree with the order of the output
+	unsorted := `package test
+
+type Server struct{}
+
+func (s *Server) Stop() {}
+func (s *Server) Start() {}
+`
+	metrics := NewCallGraph()
+	metrics.AddMethod(&MethodInfo{ReceiverName: "Server", Name: "Start"})
+	metrics.AddMethod(&MethodInfo{ReceiverName: "Server", Name: "Stop", MaxDepth: 1})
+
+	err := checkIdempotent([]byte(unsorted), depthStrategy{}, config.DefaultConfig().SortCriteria, metrics)
+	if !errors.Is(err, ErrNotIdempotent) {
+		t.Fatalf("Expected ErrNotIdempotent, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "moves Server.Stop") {
+		t.Errorf("Expected the error to name the moved method, got %v", err)
+	}
+}
+
+func TestSorterSortsBlankMethods(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (s *Server) helper() {}
+func (s *Server) _()      { s.helper() }
+func (s *Server) Start()  {}
+func (s *Server) _()      {}
+`
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorter.SetDebug(true)
+
+	sorted, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected Start to move ahead of the unexported methods")
+	}
+	if err := Verify([]byte(source), sorted); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both blank methods are kept and sorted; neither calls helper, since
+	// a blank method never runs
+	var order []string
+	for _, placement := range sorter.Placements() {
+		order = append(order, placement.Name)
+	}
+	expected := []string{"Start", "helper", "_", "_"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected %v, got %v", expected, order)
+	}
+}
+
+func TestNewFromSourceWithoutPackageClause(t *testing.T) {
+	for _, source := range []string{"", "package", "func main() {}"} {
+		if _, err := NewFromSource(source); err == nil {
+			t.Errorf("Expected an error for %q", source)
+		}
+	}
+}
+
+// FuzzSort checks that sorting never breaks a file: the output parses, keeps
+// every declaration and comment, and sorting it again changes nothing
+func FuzzSort(f *testing.F) {
+	f.Add(`package test
+
+type Server struct{}
+
+// helper is shared
+func (s *Server) helper() {}
+
+func (s *Server) Start() error {
+	s.helper()
+	return nil
+}
+
+func NewServer() *Server { return &Server{} }
+`, false)
+	f.Add(`package test
+
+type List[T any] struct{ items []T }
+
+func (l *List[T]) push(item T) { l.items = append(l.items, item) }
+
+// Add adds
+func (l *List[T]) Add(item T) { l.push(item) }
+
+type Map[K comparable, V any] map[K]V
+
+func (m Map[K, V]) Get(key K) V { return m[key] }
+`, true)
+	f.Add(`package test
+
+type U struct{}
+
+func (U) _() {}
+func (U) b() {}
+func (U) _() {}
+func (U) A() {}
+`, false)
+	if source, err := os.ReadFile("../../testdata/complex_example.go"); err == nil {
+		f.Add(string(source), false)
+		f.Add(string(source), true)
+	}
+
+	f.Fuzz(func(t *testing.T, source string, afterType bool) {
+		sorter, err := NewFromSource(source)
+		if err != nil {
+			return
+		}
+
+		criteria := config.DefaultConfig().SortCriteria
+		if afterType {
+			criteria.Layout = config.LayoutAfterType
+		}
+		sorter.SetCriteria(criteria)
+		sorter.SetDebug(true)
+
+		sorted, changed, err := sorter.Sort()
+		if err != nil {
+			t.Fatalf("Sort failed: %v\nSource:\n%s", err, source)
+		}
+		if !changed {
+			return
+		}
+
+		if err := Verify([]byte(source), sorted); err != nil {
+			t.Fatalf("%v\nSource:\n%s\nSorted:\n%s", err, source, sorted)
+		}
+	})
+}
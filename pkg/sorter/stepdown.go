@@ -0,0 +1,137 @@
+package sorter
+
+import (
+	"sort"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+// stepdownStrategy is the strategy registered as config.StrategyStepdown
+type stepdownStrategy struct{}
+
+func (stepdownStrategy) Order(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+	return stepdownOrder(methods, graph, criteria)
+}
+
+func (stepdownStrategy) criterion(a, b *MethodInfo, criteria config.SortCriteria) Criterion {
+	if criteria.GroupByReceiver && a.ReceiverName != b.ReceiverName {
+		return CriterionReceiver
+	}
+	return CriterionCallOrder
+}
+
+// stepdownOrder orders methods so that the file reads top-down: each entry
+// point is followed, depth-first, by the methods it calls, each right after
+// its first caller. A method called from several places instead comes after
+// the last of them. methods must be in their original order.
+func stepdownOrder(methods []*MethodInfo, cg *CallGraph, criteria config.SortCriteria) []*MethodInfo {
+	groups := [][]*MethodInfo{methods}
+	if criteria.GroupByReceiver {
+		groups = receiverGroups(methods)
+	}
+
+	order := make([]*MethodInfo, 0, len(methods))
+	for _, group := range groups {
+		order = append(order, stepdownGroup(group, cg, criteria)...)
+	}
+	return order
+}
+
+// receiverGroups splits methods by receiver, ordered by receiver name
+func receiverGroups(methods []*MethodInfo) [][]*MethodInfo {
+	index := make(map[string]int)
+	var groups [][]*MethodInfo
+	for _, method := range methods {
+		i, ok := index[method.ReceiverName]
+		if !ok {
+			i = len(groups)
+			index[method.ReceiverName] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], method)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i][0].ReceiverName < groups[j][0].ReceiverName
+	})
+	return groups
+}
+
+func stepdownGroup(methods []*MethodInfo, cg *CallGraph, criteria config.SortCriteria) []*MethodInfo {
+	byKey := make(map[string]*MethodInfo, len(methods))
+	for _, method := range methods {
+		byKey[method.key()] = method
+	}
+
+	// Only calls between the methods being ordered count. The graph may be
+	// the package's, whose methods are other values than the file's.
+	callees := make(map[*MethodInfo][]*MethodInfo)
+	callers := make(map[*MethodInfo]int)
+	for _, method := range methods {
+		for _, called := range cg.Calls(method) {
+			callee := byKey[called.key()]
+			if callee == nil || callee == method {
+				continue
+			}
+			callees[method] = append(callees[method], callee)
+			callers[callee]++
+		}
+	}
+
+	// Entry points, and methods only reached through a cycle, are taken
+	// exported first if asked to, then in their original order
+	candidates := make([]*MethodInfo, len(methods))
+	copy(candidates, methods)
+	if criteria.ExportedFirst {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].IsExported && !candidates[j].IsExported
+		})
+	}
+
+	order := make([]*MethodInfo, 0, len(methods))
+	placed := make(map[*MethodInfo]bool, len(methods))
+	called := make(map[*MethodInfo]bool)
+	var place func(method *MethodInfo)
+	place = func(method *MethodInfo) {
+		placed[method] = true
+		order = append(order, method)
+		for _, callee := range callees[method] {
+			if placed[callee] {
+				continue
+			}
+			called[callee] = true
+			callers[callee]--
+			if callers[callee] == 0 {
+				place(callee)
+			}
+		}
+	}
+
+	for len(order) < len(methods) {
+		place(nextEntry(candidates, placed, callers, called))
+	}
+	return order
+}
+
+// nextEntry returns the next method to start a depth-first walk from: an
+// entry point if one is left, otherwise a method in a cycle, preferably one
+// that an already placed method calls
+func nextEntry(candidates []*MethodInfo, placed map[*MethodInfo]bool, callers map[*MethodInfo]int, called map[*MethodInfo]bool) *MethodInfo {
+	var cycle, calledCycle *MethodInfo
+	for _, method := range candidates {
+		switch {
+		case placed[method]:
+		case callers[method] == 0:
+			return method
+		case called[method] && calledCycle == nil:
+			calledCycle = method
+		case cycle == nil:
+			cycle = method
+		}
+	}
+
+	if calledCycle != nil {
+		return calledCycle
+	}
+	return cycle
+}
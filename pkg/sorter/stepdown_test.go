@@ -0,0 +1,175 @@
+package sorter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+func TestStepdownOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		exportedFirst bool
+		expected      []string
+	}{
+		{
+			name: "callees follow their first caller depth-first",
+			source: `package test
+
+type S struct{}
+
+func (s *S) c() {}
+func (s *S) b() {}
+func (s *S) a() { s.c() }
+func (s *S) Run() { s.a(); s.b() }
+`,
+			exportedFirst: true,
+			expected:      []string{"S.Run", "S.a", "S.c", "S.b"},
+		},
+		{
+			name: "shared helper comes after its last caller",
+			source: `package test
+
+type S struct{}
+
+func (s *S) log() {}
+func (s *S) Stop() { s.log() }
+func (s *S) validate() {}
+func (s *S) Start() { s.validate(); s.log() }
+`,
+			exportedFirst: true,
+			expected:      []string{"S.Stop", "S.Start", "S.validate", "S.log"},
+		},
+		{
+			name: "cycle is entered from its caller",
+			source: `package test
+
+type S struct{}
+
+func (s *S) b() { s.a() }
+func (s *S) a() { s.b() }
+func (s *S) Run() { s.a() }
+`,
+			exportedFirst: true,
+			expected:      []string{"S.Run", "S.a", "S.b"},
+		},
+		{
+			name: "cycle without entry point starts at its first method",
+			source: `package test
+
+type S struct{}
+
+func (s *S) b() { s.a() }
+func (s *S) a() { s.b() }
+`,
+			exportedFirst: true,
+			expected:      []string{"S.b", "S.a"},
+		},
+		{
+			name: "recursion does not hold a method back",
+			source: `package test
+
+type S struct{}
+
+func (s *S) helper() {}
+func (s *S) Walk() { s.Walk(); s.helper() }
+`,
+			exportedFirst: true,
+			expected:      []string{"S.Walk", "S.helper"},
+		},
+		{
+			name: "entry points keep their order without exported first",
+			source: `package test
+
+type S struct{}
+
+func (s *S) helper() {}
+func (s *S) setup() { s.helper() }
+func (s *S) Run() {}
+`,
+			exportedFirst: false,
+			expected:      []string{"S.setup", "S.helper", "S.Run"},
+		},
+		{
+			name: "receivers are grouped by name",
+			source: `package test
+
+type B struct{}
+type A struct{}
+
+func (b *B) helper() {}
+func (b *B) Run() { b.helper() }
+func (a *A) Do() {}
+`,
+			exportedFirst: true,
+			expected:      []string{"A.Do", "B.Run", "B.helper"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter, err := NewFromSource(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			criteria := config.DefaultConfig().SortCriteria
+			criteria.Strategy = config.StrategyStepdown
+			criteria.ExportedFirst = tt.exportedFirst
+			sorter.SetCriteria(criteria)
+			sorter.SetDebug(true)
+
+			if _, _, err := sorter.Sort(); err != nil {
+				t.Fatal(err)
+			}
+
+			var order []string
+			for _, placement := range sorter.Placements() {
+				order = append(order, methodKey(placement.ReceiverName, placement.Name))
+			}
+			if !reflect.DeepEqual(order, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, order)
+			}
+		})
+	}
+}
+
+func TestStepdownExplainsCallOrder(t *testing.T) {
+	source := `package test
+
+type S struct{}
+
+func (s *S) helper() {}
+func (s *S) Run() { s.helper() }
+`
+	sorter, err := NewFromSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Strategy = config.StrategyStepdown
+	sorter.SetCriteria(criteria)
+
+	output, changed, err := sorter.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected helper to move after Run")
+	}
+	if strings.Index(string(output), "Run()") > strings.Index(string(output), "helper()") {
+		t.Errorf("Expected Run before helper, got:\n%s", output)
+	}
+
+	placements := sorter.Placements()
+	if len(placements) != 2 || placements[1].After != "Run" || placements[1].Criterion != CriterionCallOrder {
+		t.Errorf("Expected helper placed after Run by call order, got %+v", placements)
+	}
+
+	misplaced := sorter.Misplaced()
+	if len(misplaced) != 1 || misplaced[0].Message != "method Run should precede helper in call order" {
+		t.Errorf("Expected Run to be reported in call order, got %+v", misplaced)
+	}
+}
@@ -0,0 +1,139 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+// Strategy decides the order of a file's methods. Order gets the methods in
+// their original order, with their metrics set, and the call graph they were
+// computed from, which may be the whole package's. It returns the same
+// methods in their new order. Where the methods end up in the file is left to
+// the layout, which places each receiver's constructors before its first
+// method.
+type Strategy interface {
+	Order(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo
+}
+
+// StrategyFunc adapts an ordinary function to a Strategy
+type StrategyFunc func(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo
+
+func (f StrategyFunc) Order(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+	return f(graph, methods, criteria)
+}
+
+// ErrUnknownStrategy is returned for a strategy name that is not registered
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// ErrInvalidOrder is returned by Sort when a strategy does not return each
+// method exactly once
+var ErrInvalidOrder = errors.New("strategy returned an invalid order")
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{
+		config.StrategyDepth:    depthStrategy{},
+		config.StrategyStepdown: stepdownStrategy{},
+	}
+)
+
+// RegisterStrategy makes strategy available under name, for the strategy
+// setting and the -strategy flags. Like other registries it is meant to be
+// called from init functions, and panics if name is empty or taken.
+func RegisterStrategy(name string, strategy Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	if name == "" || strategy == nil {
+		panic("sorter: RegisterStrategy needs a name and a strategy")
+	}
+	if _, ok := strategies[name]; ok {
+		panic("sorter: strategy " + name + " registered twice")
+	}
+	strategies[name] = strategy
+}
+
+// LookupStrategy returns the strategy registered under name. The empty name
+// is the default, config.StrategyDepth.
+func LookupStrategy(name string) (Strategy, error) {
+	if name == "" {
+		name = config.StrategyDepth
+	}
+
+	strategiesMu.RLock()
+	strategy, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q (want one of %s)", ErrUnknownStrategy, name, strings.Join(Strategies(), ", "))
+	}
+	return strategy, nil
+}
+
+// Strategies returns the names of the registered strategies, sorted
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// depthStrategy is the strategy registered as config.StrategyDepth, which
+// orders methods by the sort criteria
+type depthStrategy struct{}
+
+func (depthStrategy) Order(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+	return sortMethods(methods, criteria)
+}
+
+func (depthStrategy) criterion(a, b *MethodInfo, criteria config.SortCriteria) Criterion {
+	return decidingCriterion(a.SortKey(), b.SortKey(), criteria)
+}
+
+// explainer is implemented by the built-in strategies, which can tell the
+// rule that puts one method before another
+type explainer interface {
+	criterion(a, b *MethodInfo, criteria config.SortCriteria) Criterion
+}
+
+// orderingCriterion returns the rule that puts a before b in the order of
+// strategy, CriterionStrategy if the strategy cannot tell
+func orderingCriterion(strategy Strategy, a, b *MethodInfo, criteria config.SortCriteria) Criterion {
+	if explainer, ok := strategy.(explainer); ok {
+		return explainer.criterion(a, b, criteria)
+	}
+	return CriterionStrategy
+}
+
+// checkOrder makes sure order holds each of methods exactly once, since a
+// method left out would be deleted from the file
+func checkOrder(methods, order []*MethodInfo) error {
+	if len(order) != len(methods) {
+		return fmt.Errorf("%w: %d methods instead of %d", ErrInvalidOrder, len(order), len(methods))
+	}
+
+	remaining := make(map[*MethodInfo]bool, len(methods))
+	for _, method := range methods {
+		remaining[method] = true
+	}
+	for _, method := range order {
+		if !remaining[method] {
+			if method == nil {
+				return fmt.Errorf("%w: nil method", ErrInvalidOrder)
+			}
+			return fmt.Errorf("%w: %s is not one of the methods or appears twice",
+				ErrInvalidOrder, methodKey(method.ReceiverName, method.Name))
+		}
+		delete(remaining, method)
+	}
+	return nil
+}
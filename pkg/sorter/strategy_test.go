@@ -0,0 +1,179 @@
+package sorter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+// reverseStrategy puts methods in the reverse of their original order
+var reverseStrategy = StrategyFunc(func(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+	order := make([]*MethodInfo, 0, len(methods))
+	for i := len(methods) - 1; i >= 0; i-- {
+		order = append(order, methods[i])
+	}
+	return order
+})
+
+func init() {
+	RegisterStrategy("test-reverse", reverseStrategy)
+}
+
+const strategySource = `package test
+
+type Server struct{}
+
+func (s *Server) Start() { s.helper() }
+func (s *Server) helper() {}
+func (s *Server) Stop() {}
+`
+
+func TestLookupStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Strategy
+	}{
+		{"", depthStrategy{}},
+		{config.StrategyDepth, depthStrategy{}},
+		{config.StrategyStepdown, stepdownStrategy{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := LookupStrategy(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strategy != tt.expected {
+				t.Errorf("Expected %T, got %T", tt.expected, strategy)
+			}
+		})
+	}
+
+	if _, err := LookupStrategy("alphabetical"); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("Expected ErrUnknownStrategy, got %v", err)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	expected := []string{config.StrategyDepth, config.StrategyStepdown, "test-reverse"}
+	if names := Strategies(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestRegisterStrategyPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+	}{
+		{config.StrategyDepth, reverseStrategy},
+		{"", reverseStrategy},
+		{"test-nil", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected RegisterStrategy to panic")
+				}
+			}()
+			RegisterStrategy(tt.name, tt.strategy)
+		})
+	}
+}
+
+func TestSorterRegisteredStrategy(t *testing.T) {
+	sorter, err := NewFromSource(strategySource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Strategy = "test-reverse"
+	sorter.SetCriteria(criteria)
+
+	if _, _, err := sorter.Sort(); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, placement := range sorter.Placements() {
+		order = append(order, placement.Name)
+	}
+	expected := []string{"Stop", "helper", "Start"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected %v, got %v", expected, order)
+	}
+
+	// The sorter cannot tell why a strategy of its own chose an order
+	if criterion := sorter.Placements()[1].Criterion; criterion != CriterionStrategy {
+		t.Errorf("Expected CriterionStrategy, got %v", criterion)
+	}
+}
+
+func TestSorterSetStrategy(t *testing.T) {
+	sorter, err := NewFromSource(strategySource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Strategy = "not registered"
+	sorter.SetCriteria(criteria)
+	sorter.SetStrategy(reverseStrategy)
+
+	if _, changed, err := sorter.Sort(); err != nil || !changed {
+		t.Fatalf("Expected the set strategy to reorder the file, got changed=%v, err=%v", changed, err)
+	}
+}
+
+func TestSorterUnknownStrategy(t *testing.T) {
+	sorter, err := NewFromSource(strategySource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criteria := config.DefaultConfig().SortCriteria
+	criteria.Strategy = "alphabetical"
+	sorter.SetCriteria(criteria)
+
+	if _, _, err := sorter.Sort(); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("Expected ErrUnknownStrategy, got %v", err)
+	}
+}
+
+func TestSorterRejectsInvalidOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy StrategyFunc
+	}{
+		{"method dropped", func(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+			return methods[1:]
+		}},
+		{"method repeated", func(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+			return append(methods[1:], methods[1])
+		}},
+		{"foreign method", func(graph *CallGraph, methods []*MethodInfo, criteria config.SortCriteria) []*MethodInfo {
+			return append(methods[1:], &MethodInfo{ReceiverName: "Server", Name: "Start"})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter, err := NewFromSource(strategySource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorter.SetStrategy(tt.strategy)
+
+			output, _, err := sorter.Sort()
+			if !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("Expected ErrInvalidOrder, got %v", err)
+			}
+			if output != nil {
+				t.Errorf("Expected no output, got:\n%s", output)
+			}
+		})
+	}
+}
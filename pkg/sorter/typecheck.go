@@ -0,0 +1,206 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+
+	"github.com/dave/dst"
+	"golang.org/x/tools/go/packages"
+)
+
+// Dependencies are type-checked from source rather than read from export
+// data, so loading does not depend on the toolchain's export format
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+	packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// LoadPackage type-checks the package in dir and returns a sorter for each of
+// its Go files, keyed by absolute file name. These sorters resolve calls
+// through types.Info instead of guessing from receiver names.
+func LoadPackage(dir string) (map[string]*Sorter, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := LoadPackages([]string{absDir})[absDir]
+	return loaded.Sorters, loaded.Err
+}
+
+// LoadedPackage is what LoadPackages found in one directory: the sorters of
+// its package, keyed by absolute file name, or the error loading it
+type LoadedPackage struct {
+	Sorters map[string]*Sorter
+	Err     error
+}
+
+// LoadPackages type-checks the packages in dirs as LoadPackage does, keyed by
+// absolute directory. The packages of each module are loaded together, so
+// that their dependencies are checked once rather than once per directory.
+func LoadPackages(dirs []string) map[string]*LoadedPackage {
+	loaded := make(map[string]*LoadedPackage, len(dirs))
+	modules := make(map[string][]string)
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			loaded[dir] = &LoadedPackage{Err: err}
+			continue
+		}
+		if _, ok := loaded[absDir]; ok {
+			continue
+		}
+		loaded[absDir] = &LoadedPackage{
+			Err: fmt.Errorf("loading package in %s: no package found", absDir),
+		}
+
+		root := moduleRoot(absDir)
+		modules[root] = append(modules[root], absDir)
+	}
+
+	for root, moduleDirs := range modules {
+		loadModulePackages(root, moduleDirs, loaded)
+	}
+	return loaded
+}
+
+// loadModulePackages loads the packages in dirs, which belong to the module
+// at root, into loaded
+func loadModulePackages(root string, dirs []string, loaded map[string]*LoadedPackage) {
+	cfg := &packages.Config{
+		Mode: loadMode,
+		Dir:  root,
+	}
+
+	pkgs, err := packages.Load(cfg, dirs...)
+	if err != nil {
+		for _, dir := range dirs {
+			loaded[dir].Err = fmt.Errorf("loading package in %s: %w", dir, err)
+		}
+		return
+	}
+
+	for _, pkg := range pkgs {
+		if result, ok := loaded[pkg.Dir]; ok {
+			result.Sorters, result.Err = packageSorters(pkg)
+		}
+	}
+}
+
+// packageSorters returns a sorter for each Go file of a loaded package
+func packageSorters(pkg *packages.Package) (map[string]*Sorter, error) {
+	if len(pkg.Errors) > 0 {
+		errs := make([]error, 0, len(pkg.Errors))
+		for _, pkgErr := range pkg.Errors {
+			errs = append(errs, pkgErr)
+		}
+		return nil, fmt.Errorf("type-checking %s: %w", pkg.PkgPath, errors.Join(errs...))
+	}
+
+	sorters := make(map[string]*Sorter)
+	for _, file := range pkg.Syntax {
+		filename, err := filepath.Abs(pkg.Fset.File(file.Pos()).Name())
+		if err != nil {
+			return nil, err
+		}
+
+		sorter, err := NewFromFile(pkg.Fset, file, pkg.TypesInfo)
+		if err != nil {
+			return nil, fmt.Errorf("decorating %s: %w", filename, err)
+		}
+		sorters[filename] = sorter
+	}
+	return sorters, nil
+}
+
+// moduleRoot returns the directory holding the go.mod above dir, or dir
+// itself outside a module
+func moduleRoot(dir string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return dir
+		}
+	}
+}
+
+// buildTypedCallGraph builds the call graph of files type-checked together
+// with info; astNodes maps their dst nodes back to the checked syntax
+func buildTypedCallGraph(files []*dst.File, astNodes map[dst.Node]ast.Node, info *types.Info) *CallGraph {
+	cg := NewCallGraph()
+
+	var decls []dst.Decl
+	for _, file := range files {
+		decls = append(decls, file.Decls...)
+	}
+
+	// First pass: collect all methods and their type-checker objects
+	funcs := make(map[*types.Func]*MethodInfo)
+	bodies := make(map[*MethodInfo]*ast.BlockStmt)
+	position := 0
+	for _, decl := range decls {
+		funcDecl, ok := decl.(*dst.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		method := extractMethodInfo(funcDecl, position)
+		if method == nil {
+			continue
+		}
+		cg.AddMethod(method)
+		position++
+
+		astDecl, ok := astNodes[funcDecl].(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if obj, ok := info.Defs[astDecl.Name].(*types.Func); ok {
+			funcs[obj] = method
+		}
+		if astDecl.Body != nil && !method.isBlank() {
+			bodies[method] = astDecl.Body
+		}
+	}
+
+	// Second pass: resolve every method selector, which covers plain calls,
+	// promoted methods, method values and method expressions alike
+	for _, method := range cg.GetMethods() {
+		body, ok := bodies[method]
+		if !ok {
+			continue
+		}
+
+		ast.Inspect(body, func(node ast.Node) bool {
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			selection, ok := info.Selections[sel]
+			if !ok || selection.Kind() == types.FieldVal {
+				return true
+			}
+
+			callee, ok := selection.Obj().(*types.Func)
+			if !ok {
+				return true
+			}
+
+			// As in the heuristic graph, only calls within one type count,
+			// so that type information makes the metrics exact without
+			// changing what they measure
+			if target, ok := funcs[callee.Origin()]; ok && target.ReceiverName == method.ReceiverName {
+				cg.addCall(method.key(), target.key())
+			}
+			return true
+		})
+	}
+
+	cg.CalculateMetrics()
+	return cg
+}
@@ -0,0 +1,264 @@
+package sorter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module testmodule\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func loadTestSorter(t *testing.T, dir, name string) *Sorter {
+	t.Helper()
+
+	sorters, err := LoadPackage(dir)
+	if err != nil {
+		t.Fatalf("LoadPackage() failed: %v", err)
+	}
+
+	filename, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := sorters[filename]
+	if !ok {
+		t.Fatalf("No sorter for %s, got %v", filename, sorters)
+	}
+	return s
+}
+
+func TestTypedCallGraphResolvesReceivers(t *testing.T) {
+	source := `package test
+
+import "bytes"
+
+type Server struct{}
+
+func (srv *Server) Start() error {
+	srv.helper()
+	return nil
+}
+
+func (srv *Server) helper() {}
+
+type Buffer struct{}
+
+func (b *Buffer) Flush() {
+	var local bytes.Buffer
+	b2 := &local
+	b2.Reset()
+	{
+		b := &local
+		b.Reset()
+	}
+}
+
+func (b *Buffer) Reset() {}
+`
+
+	dir := writeTestPackage(t, map[string]string{"server.go": source})
+	cg := loadTestSorter(t, dir, "server.go").buildCallGraph()
+
+	if calls := cg.calls[methodKey("Server", "Start")]; len(calls) != 1 || calls[0] != "Server.helper" {
+		t.Errorf("Expected Server.Start -> Server.helper, got %v", calls)
+	}
+
+	// b.Reset() on a local *bytes.Buffer must not count as a call to Buffer.Reset
+	if calls := cg.calls[methodKey("Buffer", "Flush")]; len(calls) != 0 {
+		t.Errorf("Expected no calls from Buffer.Flush, got %v", calls)
+	}
+
+	// The heuristic graph gets both wrong
+	heuristic := buildCallGraph(loadTestSorter(t, dir, "server.go").file)
+	if calls := heuristic.calls[methodKey("Server", "Start")]; len(calls) != 0 {
+		t.Errorf("Expected heuristic graph to miss srv.helper(), got %v", calls)
+	}
+}
+
+func TestTypedCallGraphEmbeddedAndMethodValues(t *testing.T) {
+	source := `package test
+
+type Base struct{}
+
+func (b *Base) close() {}
+
+type Server struct {
+	*Base
+}
+
+func (s *Server) Run() {
+	f := s.prepare
+	f()
+	g := (*Server).finish
+	g(s)
+	s.close()
+}
+
+func (s *Server) prepare() {}
+
+func (s *Server) finish() {}
+`
+
+	dir := writeTestPackage(t, map[string]string{"server.go": source})
+	cg := loadTestSorter(t, dir, "server.go").buildCallGraph()
+
+	// The promoted Base.close belongs to another type, so its call does
+	// not count
+	calls := cg.calls[methodKey("Server", "Run")]
+	expected := []string{"Server.prepare", "Server.finish"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected calls %v, got %v", expected, calls)
+	}
+
+	if cg.methods[methodKey("Base", "close")].InDegree != 0 {
+		t.Errorf("Expected promoted Base.close to have in-degree 0, got %d",
+			cg.methods[methodKey("Base", "close")].InDegree)
+	}
+}
+
+func TestTypedCallGraphIgnoresOtherTypes(t *testing.T) {
+	source := `package test
+
+type Client struct{}
+
+func (c *Client) Do() {
+	c.send()
+}
+
+func (c *Client) send() {}
+
+type Server struct {
+	c *Client
+}
+
+func (s *Server) handle() {
+	s.c.Do()
+}
+`
+
+	dir := writeTestPackage(t, map[string]string{"server.go": source})
+	cg := loadTestSorter(t, dir, "server.go").buildCallGraph()
+
+	handle := cg.methods[methodKey("Server", "handle")]
+	if calls := cg.calls[methodKey("Server", "handle")]; len(calls) != 0 || handle.MaxDepth != 0 {
+		t.Errorf("Expected Server.handle to call nothing of its own type, got calls %v and depth %d", calls, handle.MaxDepth)
+	}
+	if do := cg.methods[methodKey("Client", "Do")]; do.InDegree != 0 || do.MaxDepth != 1 {
+		t.Errorf("Expected Client.Do to have in-degree 0 and depth 1, got %d and %d", do.InDegree, do.MaxDepth)
+	}
+}
+
+func TestTypedCallGraphGenericReceivers(t *testing.T) {
+	source := `package test
+
+type Map[K comparable, V any] struct{}
+
+func (m *Map[K, V]) Set(k K, v V) {
+	m.grow()
+}
+
+func (m *Map[K, V]) grow() {}
+
+func use() {
+	var ints Map[string, int]
+	ints.Set("a", 1)
+}
+`
+
+	dir := writeTestPackage(t, map[string]string{"map.go": source})
+	cg := loadTestSorter(t, dir, "map.go").buildCallGraph()
+
+	if calls := cg.calls[methodKey("Map", "Set")]; len(calls) != 1 || calls[0] != "Map.grow" {
+		t.Errorf("Expected Map.Set -> Map.grow, got %v", calls)
+	}
+}
+
+func TestTypedSorterSort(t *testing.T) {
+	source := `package test
+
+type Server struct{}
+
+func (srv *Server) connect() {
+	srv.dial()
+}
+
+func (srv *Server) dial() {}
+
+func (srv *Server) Start() {
+	srv.connect()
+}
+`
+
+	dir := writeTestPackage(t, map[string]string{"server.go": source})
+	sorted, changed, err := loadTestSorter(t, dir, "server.go").Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected methods to be reordered")
+	}
+
+	// dial (depth 0) sorts before connect (depth 1), which the heuristic
+	// graph cannot see because the receiver is called srv
+	sortedCode := string(sorted)
+	startIndex := strings.Index(sortedCode, "func (srv *Server) Start()")
+	dialIndex := strings.Index(sortedCode, "func (srv *Server) dial()")
+	connectIndex := strings.Index(sortedCode, "func (srv *Server) connect()")
+	if startIndex > dialIndex || dialIndex > connectIndex {
+		t.Errorf("Unexpected order:\n%s", sortedCode)
+	}
+}
+
+func TestLoadPackageWithTypeErrors(t *testing.T) {
+	dir := writeTestPackage(t, map[string]string{"broken.go": "package test\n\nvar x int = \"string\"\n"})
+
+	if _, err := LoadPackage(dir); err == nil {
+		t.Error("Expected error for package with type errors")
+	}
+}
+
+func TestLoadPackages(t *testing.T) {
+	dir := writeTestPackage(t, map[string]string{"server.go": "package test\n\ntype Server struct{}\n\nfunc (s *Server) Start() {}\n"})
+	for name, content := range map[string]string{
+		"client/client.go": "package client\n\ntype Client struct{}\n\nfunc (c *Client) Do() {}\n",
+		"broken/broken.go": "package broken\n\nvar x int = \"string\"\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A package that does not type-check only fails its own directory
+	dirs := []string{dir, filepath.Join(dir, "client"), filepath.Join(dir, "broken")}
+	loaded := LoadPackages(dirs)
+	for _, d := range dirs[:2] {
+		result, ok := loaded[d]
+		if !ok || result.Err != nil || len(result.Sorters) != 1 {
+			t.Errorf("Expected one sorter for %s, got %+v", d, result)
+		}
+	}
+	if result, ok := loaded[dirs[2]]; !ok || result.Err == nil {
+		t.Errorf("Expected an error for %s, got %+v", dirs[2], result)
+	}
+}
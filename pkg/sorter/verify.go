@@ -0,0 +1,223 @@
+package sorter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// ErrVerify is returned by Verify when the sorted output is not a
+// reordering of the input
+var ErrVerify = errors.New("sorted output does not match the input")
+
+type fileSummary struct {
+	pkg         string
+	constraints []string
+	decls       []summaryEntry
+	comments    []string
+}
+
+type summaryEntry struct {
+	label string
+	text  string
+}
+
+// Verify checks that sorted holds exactly the top-level declarations,
+// comments and build constraints of original, with only the order of the
+// declarations changed. Declarations are compared by their printed syntax.
+// Both sides are formatted with gofmt first, since the sorter prints its
+// output the way gofmt does.
+func Verify(original, sorted []byte) error {
+	before, err := summarize(original)
+	if err != nil {
+		return fmt.Errorf("parsing input: %w", err)
+	}
+
+	after, err := summarize(sorted)
+	if err != nil {
+		return fmt.Errorf("%w: output does not parse: %v", ErrVerify, err)
+	}
+
+	if before.pkg != after.pkg {
+		return fmt.Errorf("%w: package changed from %s to %s", ErrVerify, before.pkg, after.pkg)
+	}
+
+	if strings.Join(before.constraints, "\n") != strings.Join(after.constraints, "\n") {
+		return fmt.Errorf("%w: build constraints changed", ErrVerify)
+	}
+
+	if entry, ok := missing(before.decls, after.decls); ok {
+		return fmt.Errorf("%w: %s changed or lost", ErrVerify, entry.label)
+	}
+	if entry, ok := missing(after.decls, before.decls); ok {
+		return fmt.Errorf("%w: %s added", ErrVerify, entry.label)
+	}
+
+	if comment, ok := missing(commentEntries(before.comments), commentEntries(after.comments)); ok {
+		return fmt.Errorf("%w: comment %s lost", ErrVerify, comment.label)
+	}
+	if comment, ok := missing(commentEntries(after.comments), commentEntries(before.comments)); ok {
+		return fmt.Errorf("%w: comment %s added", ErrVerify, comment.label)
+	}
+
+	return nil
+}
+
+func summarize(source []byte) (*fileSummary, error) {
+	// Build constraints are taken from the source as is, since gofmt
+	// would move a misplaced one back to the top
+	file, err := parser.ParseFile(token.NewFileSet(), "", source, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &fileSummary{pkg: file.Name.Name}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if comment.Pos() < file.Package && isConstraint(comment.Text) {
+				summary.constraints = append(summary.constraints, comment.Text)
+			}
+		}
+	}
+
+	formatted, err := format.Source(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err = parser.ParseFile(fset, "", formatted, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// gofmt may drop an empty doc comment on a second pass, so those are
+	// not compared
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if strings.TrimSpace(strings.TrimPrefix(comment.Text, "//")) == "" {
+				continue
+			}
+			summary.comments = append(summary.comments, comment.Text)
+		}
+	}
+
+	// Printing a declaration on its own leaves out the comments, which are
+	// compared separately. Imports are compared one by one, since the
+	// printer may sort them within a block.
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			for _, spec := range gen.Specs {
+				imp, ok := spec.(*ast.ImportSpec)
+				if !ok {
+					continue
+				}
+				text := imp.Path.Value
+				if imp.Name != nil {
+					text = imp.Name.Name + " " + text
+				}
+				summary.decls = append(summary.decls, summaryEntry{label: "import " + imp.Path.Value, text: text})
+			}
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, stripDoc(decl)); err != nil {
+			return nil, err
+		}
+		summary.decls = append(summary.decls, summaryEntry{label: declLabel(decl), text: dropBlankLines(buf.String())})
+	}
+
+	return summary, nil
+}
+
+// dropBlankLines removes the empty lines of a printed declaration; the
+// printer does not always keep those within a type parameter list
+func dropBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isConstraint(text string) bool {
+	return strings.HasPrefix(text, "//go:build") || strings.HasPrefix(text, "// +build")
+}
+
+// stripDoc returns decl without its doc comment, which the printer would
+// otherwise print with it
+func stripDoc(decl ast.Decl) ast.Decl {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		stripped := *d
+		stripped.Doc = nil
+		return &stripped
+	case *ast.GenDecl:
+		stripped := *d
+		stripped.Doc = nil
+		return &stripped
+	}
+	return decl
+}
+
+func declLabel(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, token.NewFileSet(), d.Recv.List[0].Type); err != nil {
+				return "method " + d.Name.Name
+			}
+			return fmt.Sprintf("method (%s).%s", buf.String(), d.Name.Name)
+		}
+		return "function " + d.Name.Name
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				return "type " + s.Name.Name
+			case *ast.ValueSpec:
+				if len(s.Names) > 0 {
+					return fmt.Sprintf("%s %s", d.Tok, s.Names[0].Name)
+				}
+			}
+		}
+		return d.Tok.String() + " declaration"
+	}
+	return "declaration"
+}
+
+func commentEntries(comments []string) []summaryEntry {
+	entries := make([]summaryEntry, len(comments))
+	for i, comment := range comments {
+		entries[i] = summaryEntry{label: fmt.Sprintf("%q", comment), text: comment}
+	}
+	return entries
+}
+
+// missing returns the first entry of a that b does not have, counting
+// duplicates
+func missing(a, b []summaryEntry) (summaryEntry, bool) {
+	counts := make(map[string]int, len(b))
+	for _, entry := range b {
+		counts[entry.text]++
+	}
+
+	for _, entry := range a {
+		if counts[entry.text] == 0 {
+			return entry, true
+		}
+		counts[entry.text]--
+	}
+	return summaryEntry{}, false
+}
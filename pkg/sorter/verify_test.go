@@ -0,0 +1,132 @@
+package sorter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/borovikovd/gomsort/pkg/config"
+)
+
+const verifySource = `//go:build linux
+
+// Package test is a test
+package test
+
+import (
+	"fmt"
+	"os"
+)
+
+type Server struct{}
+
+// helper helps
+func (s *Server) helper() { fmt.Println("help") }
+
+func (s *Server) Start() error {
+	s.helper() // calls the helper
+	return nil
+}
+
+var _ = os.Stdout
+`
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		sorted  string
+		wantErr string
+	}{
+		{
+			name: "reordered",
+			sorted: strings.Replace(verifySource,
+				"// helper helps\nfunc (s *Server) helper() { fmt.Println(\"help\") }\n\n", "", 1) +
+				"\n// helper helps\nfunc (s *Server) helper() { fmt.Println(\"help\") }\n",
+		},
+		{
+			name:   "imports reordered",
+			sorted: strings.Replace(verifySource, "\"fmt\"\n\t\"os\"", "\"os\"\n\t\"fmt\"", 1),
+		},
+		{
+			name:   "blank line added",
+			sorted: strings.Replace(verifySource, "\treturn nil\n}", "\n\treturn nil\n}", 1),
+		},
+		{
+			name:   "empty comment added",
+			sorted: strings.Replace(verifySource, "type Server struct{}\n", "//\ntype Server struct{}\n", 1),
+		},
+		{
+			name:    "body changed",
+			sorted:  strings.Replace(verifySource, `"help"`, `"help!"`, 1),
+			wantErr: "method (*Server).helper changed or lost",
+		},
+		{
+			name:    "declaration lost",
+			sorted:  strings.Replace(verifySource, "var _ = os.Stdout\n", "", 1),
+			wantErr: "var _ changed or lost",
+		},
+		{
+			name:    "declaration added",
+			sorted:  verifySource + "\nfunc extra() {}\n",
+			wantErr: "function extra added",
+		},
+		{
+			name:    "comment lost",
+			sorted:  strings.Replace(verifySource, " // calls the helper", "", 1),
+			wantErr: `comment "// calls the helper" lost`,
+		},
+		{
+			name:    "build constraint moved",
+			sorted:  strings.Replace(verifySource, "//go:build linux\n\n", "", 1) + "\n//go:build linux\n",
+			wantErr: "build constraints changed",
+		},
+		{
+			name:    "unparseable",
+			sorted:  verifySource + "\nfunc (\n",
+			wantErr: "output does not parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify([]byte(verifySource), []byte(tt.sorted))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrVerify) {
+				t.Fatalf("Expected ErrVerify, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifySortedOutput(t *testing.T) {
+	for _, layout := range []string{config.LayoutEnd, config.LayoutAfterType} {
+		sorter, err := NewFromSource(verifySource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		criteria := config.DefaultConfig().SortCriteria
+		criteria.Layout = layout
+		sorter.SetCriteria(criteria)
+
+		sorted, changed, err := sorter.Sort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed {
+			t.Fatalf("Expected the %s layout to change the source", layout)
+		}
+
+		if err := Verify([]byte(verifySource), sorted); err != nil {
+			t.Errorf("Verify failed for the %s layout: %v", layout, err)
+		}
+	}
+}
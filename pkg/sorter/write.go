@@ -0,0 +1,74 @@
+package sorter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrFileChanged is returned by WriteFileIfUnchanged when the file no longer
+// holds the content it was sorted from
+var ErrFileChanged = errors.New("file changed since it was read")
+
+// WriteFile replaces the file's content atomically: the content goes to a
+// temporary file in the same directory, which is then renamed over the
+// original, so a crash never leaves a half-written file behind. The original
+// permissions are kept; new files are created with mode 0644.
+func WriteFile(filename string, content []byte) error {
+	// Write through symlinks instead of replacing them
+	target, err := filepath.EvalSymlinks(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		filename = target
+	}
+
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Clean up on failure; after the rename this is a no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// WriteFileIfUnchanged is WriteFile that refuses to overwrite the file if its
+// content is no longer original, for example because an editor saved it
+// while it was being sorted
+func WriteFileIfUnchanged(filename string, original, content []byte) error {
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, original) {
+		return fmt.Errorf("%w: %s", ErrFileChanged, filename)
+	}
+
+	return WriteFile(filename, content)
+}
@@ -0,0 +1,111 @@
+package sorter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFilePreservesMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not preserved on Windows")
+	}
+
+	testFile := filepath.Join(t.TempDir(), "test.go")
+	if err := os.WriteFile(testFile, []byte("package test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Chmod explicitly so the umask does not interfere
+	if err := os.Chmod(testFile, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(testFile, []byte("package test\n\nfunc Test() {}\n")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("Expected mode 0750 to be preserved, got %v", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileLeavesNoTempFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+	if err := os.WriteFile(testFile, []byte("package test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(testFile, []byte("package test\n\nfunc Test() {}\n")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the written file, got %v", entries)
+	}
+}
+
+func TestWriteFileThroughSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "target.go")
+	link := filepath.Join(tmpDir, "link.go")
+	if err := os.WriteFile(target, []byte("package test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	content := "package test\n\nfunc Test() {}\n"
+	if err := WriteFile(link, []byte(content)); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to stay a symlink", link)
+	}
+	written, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != content {
+		t.Errorf("Expected the symlink target to be written, got %q", written)
+	}
+}
+
+func TestWriteFileIfUnchanged(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.go")
+	original := []byte("package test\n")
+	if err := os.WriteFile(testFile, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sorted := []byte("package test\n\nfunc Test() {}\n")
+	if err := WriteFileIfUnchanged(testFile, original, sorted); err != nil {
+		t.Fatalf("WriteFileIfUnchanged failed: %v", err)
+	}
+
+	// The file now holds the sorted content, not the original
+	err := WriteFileIfUnchanged(testFile, original, []byte("package other\n"))
+	if !errors.Is(err, ErrFileChanged) {
+		t.Errorf("Expected ErrFileChanged, got %v", err)
+	}
+
+	written, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(sorted) {
+		t.Errorf("Expected the changed file to be left alone, got %q", written)
+	}
+}
@@ -11,24 +11,67 @@ type Database struct {
 // Complex example with various method types and call patterns
 
 // Helper method called by multiple methods (high in-degree)
+func (db *Database) validateConnection() error {
+	if db.host == "" {
+		return fmt.Errorf("host cannot be empty")
+	}
+	return nil
+}
 
 // Deep helper method (high depth)
+func (db *Database) lowLevelExecute(query string) ([]byte, error) {
+	return []byte("result"), nil
+}
 
 // Entry point method (low depth, exported)
+func (db *Database) Connect() error {
+	if err := db.validateConnection(); err != nil {
+		return err
+	}
+	return db.establishConnection()
+}
 
 // Private helper with medium depth
+func (db *Database) executeRawQuery(query string) ([]byte, error) {
+	return db.lowLevelExecute(query)
+}
 
 // Another entry point
+func (db *Database) Query(sql string) ([]Row, error) {
+	if err := db.validateConnection(); err != nil {
+		return nil, err
+	}
+	data, err := db.executeRawQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	return db.parseResults(data)
+}
 
 // Deepest level helper
+func (db *Database) performHandshake() error {
+	return nil
+}
 
 // Medium level helper
+func (db *Database) establishConnection() error {
+	return db.performHandshake()
+}
 
 // Another deep helper
+func (db *Database) parseResults(data []byte) ([]Row, error) {
+	return []Row{}, nil
+}
 
 // Entry point method (exported)
+func (db *Database) Close() error {
+	return db.cleanup()
+}
 
 // Helper for Close
+func (db *Database) cleanup() error {
+	return nil
+}
 
 // Row represents a database row
 type Row struct {
@@ -36,12 +79,28 @@ type Row struct {
 }
 
 // Simple method with no dependencies
+func (r *Row) GetString(key string) string {
+	if val, ok := r.data[key].(string); ok {
+		return val
+	}
+	return ""
+}
 
 // Method that calls another method
+func (r *Row) GetValue(key string) interface{} {
+	return r.getValue(key)
+}
 
 // Helper method
+func (r *Row) getValue(key string) interface{} {
+	return r.data[key]
+}
 
 // Another entry point
+func (r *Row) HasKey(key string) bool {
+	_, exists := r.data[key]
+	return exists
+}
 
 // Cache represents an in-memory cache
 type Cache struct {
@@ -56,8 +115,15 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 }
 
 // Helper with medium depth
+func (c *Cache) retrieve(key string) (interface{}, bool) {
+	val, ok := c.items[key]
+	return val, ok
+}
 
 // Shared helper (high in-degree)
+func (c *Cache) isValid() bool {
+	return c.items != nil
+}
 
 func (c *Cache) Set(key string, value interface{}) error {
 	if !c.isValid() {
@@ -67,15 +133,6 @@ func (c *Cache) Set(key string, value interface{}) error {
 	return nil
 }
 
-func (c *Cache) isValid() bool {
-	return c.items != nil
-}
-
-func (c *Cache) retrieve(key string) (interface{}, bool) {
-	val, ok := c.items[key]
-	return val, ok
-}
-
 func (c *Cache) store(key string, value interface{}) {
 	c.items[key] = value
 }
@@ -83,76 +140,3 @@ func (c *Cache) store(key string, value interface{}) {
 func (c *Cache) initialize() {
 	c.items = make(map[string]interface{})
 }
-
-func (db *Database) Connect() error {
-	if err := db.validateConnection(); err != nil {
-		return err
-	}
-	return db.establishConnection()
-}
-
-func (db *Database) Query(sql string) ([]Row, error) {
-	if err := db.validateConnection(); err != nil {
-		return nil, err
-	}
-	data, err := db.executeRawQuery(sql)
-	if err != nil {
-		return nil, err
-	}
-	return db.parseResults(data)
-}
-
-func (db *Database) Close() error {
-	return db.cleanup()
-}
-
-func (db *Database) validateConnection() error {
-	if db.host == "" {
-		return fmt.Errorf("host cannot be empty")
-	}
-	return nil
-}
-
-func (db *Database) executeRawQuery(query string) ([]byte, error) {
-	return db.lowLevelExecute(query)
-}
-
-func (db *Database) establishConnection() error {
-	return db.performHandshake()
-}
-
-func (db *Database) lowLevelExecute(query string) ([]byte, error) {
-	return []byte("result"), nil
-}
-
-func (db *Database) parseResults(data []byte) ([]Row, error) {
-	return []Row{}, nil
-}
-
-func (db *Database) performHandshake() error {
-	return nil
-}
-
-func (db *Database) cleanup() error {
-	return nil
-}
-
-func (r *Row) GetString(key string) string {
-	if val, ok := r.data[key].(string); ok {
-		return val
-	}
-	return ""
-}
-
-func (r *Row) HasKey(key string) bool {
-	_, exists := r.data[key]
-	return exists
-}
-
-func (r *Row) GetValue(key string) interface{} {
-	return r.getValue(key)
-}
-
-func (r *Row) getValue(key string) interface{} {
-	return r.data[key]
-}